@@ -32,20 +32,25 @@ type (
 
 	AzureMetadataInstanceResponse struct {
 		Compute struct {
-			Location             string `json:"location"`
-			Name                 string `json:"name"`
-			Offer                string `json:"offer"`
+			Location  string `json:"location"`
+			Name      string `json:"name"`
+			Offer     string `json:"offer"`
+			OsProfile struct {
+				ComputerName string `json:"computerName"`
+			} `json:"osProfile"`
 			OsType               string `json:"osType"`
 			PlacementGroupID     string `json:"placementGroupId"`
 			PlatformFaultDomain  string `json:"platformFaultDomain"`
 			PlatformUpdateDomain string `json:"platformUpdateDomain"`
 			Publisher            string `json:"publisher"`
 			ResourceGroupName    string `json:"resourceGroupName"`
+			ResourceID           string `json:"resourceId"`
 			Sku                  string `json:"sku"`
 			SubscriptionID       string `json:"subscriptionId"`
 			Tags                 string `json:"tags"`
 			Version              string `json:"version"`
 			VMID                 string `json:"vmId"`
+			VMScaleSetName       string `json:"vmScaleSetName"`
 			VMSize               string `json:"vmSize"`
 		} `json:"compute"`
 	}
@@ -69,6 +74,15 @@ func (e *AzureScheduledEvent) NotBeforeUnixTimestamp() (eventValue float64, err
 	return
 }
 
+// NotBeforeTime returns the parsed NotBefore time, zero time if ScheduledEvent has no NotBefore (eg. already started)
+func (e *AzureScheduledEvent) NotBeforeTime() (time.Time, error) {
+	if e.NotBefore == "" {
+		return time.Time{}, nil
+	}
+
+	return parseTime(e.NotBefore)
+}
+
 func parseTime(value string) (parsedTime time.Time, err error) {
 	for _, format := range timeFormatList {
 		parsedTime, err = time.Parse(format, value)
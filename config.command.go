@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	yaml "go.yaml.in/yaml/v2"
+)
+
+// runConfigCommand runs the "config" subcommands, returns exit code 1 if the config is invalid
+func runConfigCommand(name string) int {
+	switch name {
+	case "validate":
+		return runConfigValidate()
+	case "dump":
+		return runConfigDump()
+	}
+
+	fmt.Printf("unknown config command \"%v\"\n", name)
+	return 1
+}
+
+// runConfigValidate validates the effective config and prints all found problems
+func runConfigValidate() int {
+	valid := true
+
+	if err := Opts.Validate(); err != nil {
+		fmt.Println(err)
+		valid = false
+	}
+
+	if err := Opts.ValidateCommands(); err != nil {
+		fmt.Println(err)
+		valid = false
+	}
+
+	if !valid {
+		return 1
+	}
+
+	fmt.Println("config is valid")
+	return 0
+}
+
+// runConfigDump prints the effective config with redacted secrets
+func runConfigDump() int {
+	redactedOpts := Opts.Redacted()
+	values := redactedOpts.DumpValues()
+
+	var (
+		output []byte
+		err    error
+	)
+	switch ConfigDumpOpts.Output {
+	case "json":
+		output, err = json.MarshalIndent(values, "", "  ")
+		output = append(output, '\n')
+	default:
+		output, err = yaml.Marshal(values)
+	}
+
+	if err != nil {
+		logger.Error(fmt.Sprintf("unable to dump config: %v", err))
+		return 1
+	}
+
+	if _, err := os.Stdout.Write(output); err != nil {
+		return 1
+	}
+	return 0
+}
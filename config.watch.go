@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+	"github.com/webdevops/azure-scheduledevents-manager/manager"
+)
+
+// startConfigWatcher checks the content of the config file periodically (also works for
+// symlink swaps of mounted ConfigMaps) and reloads the config if it was changed
+func startConfigWatcher(scheduledEventsManager *manager.ScheduledEventsManager) {
+	lastChecksum, err := configFileChecksum(Opts.Config.File)
+	if err != nil {
+		logger.Warn("unable to read config file", slog.String("file", Opts.Config.File), slog.Any("error", err))
+	}
+
+	go func() {
+		for {
+			time.Sleep(Opts.Config.WatchInterval)
+
+			checksum, err := configFileChecksum(Opts.Config.File)
+			if err != nil {
+				logger.Warn("unable to read config file", slog.String("file", Opts.Config.File), slog.Any("error", err))
+				continue
+			}
+
+			if checksum == lastChecksum {
+				continue
+			}
+			lastChecksum = checksum
+
+			logger.Info("config file changed, reloading config", slog.String("file", Opts.Config.File))
+			reloadConfig(scheduledEventsManager)
+		}
+	}()
+}
+
+// startConfigReloadSignalHandler reloads the config on SIGHUP
+func startConfigReloadSignalHandler(scheduledEventsManager *manager.ScheduledEventsManager) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGHUP)
+
+	go func() {
+		for range signalChannel {
+			logger.Info("received SIGHUP, reloading config", slog.String("file", Opts.Config.File))
+			reloadConfig(scheduledEventsManager)
+		}
+	}()
+}
+
+// reloadConfig parses arguments, env vars and config file again and passes the config to the manager,
+// invalid configs are rejected and the current config is kept
+func reloadConfig(scheduledEventsManager *manager.ScheduledEventsManager) {
+	conf := config.Opts{}
+	if _, err := parseArgs(&conf, &config.FleetStatusOpts{}, &config.ConfigDumpOpts{}, flags.HelpFlag|flags.PassDoubleDash); err != nil {
+		logger.Error("invalid config, keeping current config", slog.Any("error", err))
+		return
+	}
+
+	if err := scheduledEventsManager.UpdateConfig(conf); err != nil {
+		logger.Error("invalid config, keeping current config", slog.Any("error", err))
+		return
+	}
+
+	logger.Info("config reloaded, applying with next collect cycle")
+}
+
+func configFileChecksum(path string) ([sha256.Size]byte, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(content), nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	OptionChange struct {
+		Option  string
+		Current string
+		Updated string
+	}
+)
+
+// Diff returns the changed options (by long option name) between the current and the updated config,
+// values of the changes are redacted
+func Diff(current, updated Opts) []OptionChange {
+	currentValues := current.Values()
+	updatedValues := updated.Values()
+
+	currentRedacted := current.Redacted()
+	updatedRedacted := updated.Redacted()
+	currentRedactedValues := currentRedacted.Values()
+	updatedRedactedValues := updatedRedacted.Values()
+
+	ret := []OptionChange{}
+	for option, updatedValue := range updatedValues {
+		if currentValues[option] != updatedValue {
+			ret = append(ret, OptionChange{Option: option, Current: currentRedactedValues[option], Updated: updatedRedactedValues[option]})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Option < ret[j].Option
+	})
+
+	return ret
+}
+
+// Values returns the values of all options by long option name
+func (o *Opts) Values() map[string]string {
+	ret := map[string]string{}
+	for name, value := range o.optionFields() {
+		ret[name] = optionValueString(value)
+	}
+	return ret
+}
+
+// DumpValues returns the values of all options (except config.* and empty strings) by long option name,
+// the result can be used as config file
+func (o *Opts) DumpValues() map[string]interface{} {
+	ret := map[string]interface{}{}
+	for name, value := range o.optionFields() {
+		if name == "config" || strings.HasPrefix(name, "config.") {
+			continue
+		}
+
+		if value.Kind() == reflect.String && value.String() == "" {
+			continue
+		}
+
+		ret[name] = optionValueDump(value)
+	}
+	return ret
+}
+
+// optionFields returns the fields of all options by long option name
+func (o *Opts) optionFields() map[string]reflect.Value {
+	ret := map[string]reflect.Value{}
+	walkOptionFields(reflect.ValueOf(*o), func(field reflect.StructField, value reflect.Value) {
+		ret[field.Tag.Get("long")] = value
+	})
+	return ret
+}
+
+// walkOptionFields calls fn for the field of every option (fields with long tag) of the (nested) struct
+func walkOptionFields(value reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+
+		if field.Tag.Get("long") != "" {
+			fn(field, fieldValue)
+		} else if fieldValue.Kind() == reflect.Struct {
+			walkOptionFields(fieldValue, fn)
+		}
+	}
+}
+
+func optionValueString(value reflect.Value) string {
+	if stringer, ok := value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Struct, reflect.Map:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return "[]"
+		}
+		if jsonValue, err := json.Marshal(value.Interface()); err == nil {
+			return string(jsonValue)
+		}
+	}
+
+	return fmt.Sprintf("%v", value.Interface())
+}
+
+func optionValueDump(value reflect.Value) interface{} {
+	if stringer, ok := value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.String {
+			ret := []string{}
+			for i := 0; i < value.Len(); i++ {
+				ret = append(ret, value.Index(i).String())
+			}
+			return ret
+		}
+
+		// eg. eviction stages, passed as JSON when loaded from config file
+		var ret interface{}
+		if jsonValue, err := json.Marshal(value.Interface()); err == nil && json.Unmarshal(jsonValue, &ret) == nil {
+			if ret == nil {
+				return []interface{}{}
+			}
+			return ret
+		}
+	}
+
+	return value.Interface()
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	// KubeDrainStageList is a list of eviction stages, passed as JSON array
+	KubeDrainStageList []KubeDrainStage
+
+	KubeDrainStage struct {
+		// name of stage (for logging)
+		Name string `json:"name"`
+
+		// label selector for pods evicted in this stage
+		Selector string `json:"selector"`
+
+		// grace period for eviction (0 = grace period of pod)
+		GracePeriod time.Duration `json:"gracePeriod"`
+
+		// wait until evicted pods are rescheduled and ready on other nodes
+		WaitReady bool `json:"waitReady"`
+
+		// number of pods evicted in parallel (0 = all)
+		Parallel int `json:"parallel"`
+
+		// max duration of stage
+		Timeout time.Duration `json:"timeout"`
+
+		// no pre-eviction hooks (eg. preempt fast path, hooks could use up the notice window)
+		SkipHooks bool `json:"-"`
+
+		// only pods also evicted by kubectl drain with --kube.drain.args (eg. pods without controller only with --force)
+		DrainArgsFilter bool `json:"-"`
+	}
+)
+
+const (
+	KubeDrainStageDefaultTimeout = 10 * time.Minute
+)
+
+// UnmarshalFlag parses eviction stages from JSON (go-flags Unmarshaler)
+func (l *KubeDrainStageList) UnmarshalFlag(value string) error {
+	if value == "" {
+		*l = nil
+		return nil
+	}
+
+	stages := KubeDrainStageList{}
+	if err := json.Unmarshal([]byte(value), &stages); err != nil {
+		return fmt.Errorf(`invalid eviction stages: %w`, err)
+	}
+
+	for num, stage := range stages {
+		if stage.Name == "" {
+			stages[num].Name = fmt.Sprintf("stage%d", num+1)
+		}
+
+		if stage.Parallel < 0 {
+			return fmt.Errorf(`invalid eviction stage "%v": parallel must not be negative`, stages[num].Name)
+		}
+
+		if stage.Timeout <= 0 {
+			stages[num].Timeout = KubeDrainStageDefaultTimeout
+		}
+	}
+
+	*l = stages
+	return nil
+}
+
+// MarshalFlag returns the eviction stages as JSON (go-flags Marshaler)
+func (l KubeDrainStageList) MarshalFlag() (string, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+
+	val, err := json.Marshal(l)
+	return string(val), err
+}
+
+func (s *KubeDrainStage) UnmarshalJSON(data []byte) error {
+	type stageAlias KubeDrainStage
+	stage := struct {
+		*stageAlias
+		GracePeriod string `json:"gracePeriod"`
+		Timeout     string `json:"timeout"`
+	}{
+		stageAlias: (*stageAlias)(s),
+	}
+
+	if err := json.Unmarshal(data, &stage); err != nil {
+		return err
+	}
+
+	var err error
+	if stage.GracePeriod != "" {
+		if s.GracePeriod, err = time.ParseDuration(stage.GracePeriod); err != nil {
+			return fmt.Errorf(`invalid gracePeriod: %w`, err)
+		}
+	}
+
+	if stage.Timeout != "" {
+		if s.Timeout, err = time.ParseDuration(stage.Timeout); err != nil {
+			return fmt.Errorf(`invalid timeout: %w`, err)
+		}
+	}
+
+	return nil
+}
+
+func (s KubeDrainStage) MarshalJSON() ([]byte, error) {
+	type stageAlias KubeDrainStage
+	return json.Marshal(struct {
+		stageAlias
+		GracePeriod string `json:"gracePeriod"`
+		Timeout     string `json:"timeout"`
+	}{
+		stageAlias:  stageAlias(s),
+		GracePeriod: s.GracePeriod.String(),
+		Timeout:     s.Timeout.String(),
+	})
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	flags "github.com/jessevdk/go-flags"
+	yaml "go.yaml.in/yaml/v2"
+)
+
+// FileArgs reads the config file (YAML, or TOML for *.toml) and converts it into arguments for the argparser.
+// Keys are the long option names, nested keys are joined with "." (eg. "drain: {not-before: 10m}" is "drain.not-before: 10m").
+// Options which are already set via arguments or env vars are skipped (precedence: flags > env > file).
+func FileArgs(path string, parser *flags.Parser) ([]string, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(content, &values)
+	} else {
+		err = yaml.Unmarshal(content, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`unable to parse config file "%v": %w`, path, err)
+	}
+
+	flatValues := map[string]interface{}{}
+	flattenConfigValues(flatValues, "", values)
+
+	keys := []string{}
+	for key := range flatValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ret := []string{}
+	for _, key := range keys {
+		if key == "config" || strings.HasPrefix(key, "config.") {
+			return nil, fmt.Errorf(`option "%v" is not allowed in config file`, key)
+		}
+
+		option := parser.FindOptionByLongName(key)
+		if option == nil {
+			return nil, fmt.Errorf(`unknown option "%v" in config file "%v"`, key, path)
+		}
+
+		// set via argument
+		if option.IsSet() && !option.IsSetDefault() {
+			continue
+		}
+
+		// set via env var
+		if envKey := option.EnvKeyWithNamespace(); envKey != "" {
+			if _, exists := os.LookupEnv(envKey); exists {
+				continue
+			}
+		}
+
+		optionValues, err := configValueToStrings(flatValues[key])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for option "%v" in config file "%v": %w`, key, path, err)
+		}
+
+		if option.Field().Type.Kind() == reflect.Bool {
+			if len(optionValues) == 1 && optionValues[0] == "true" {
+				ret = append(ret, "--"+key)
+			} else if len(optionValues) != 1 || optionValues[0] != "false" {
+				return nil, fmt.Errorf(`invalid value for option "%v" in config file "%v": must be true or false`, key, path)
+			}
+			continue
+		}
+
+		for _, val := range optionValues {
+			ret = append(ret, fmt.Sprintf("--%v=%v", key, val))
+		}
+	}
+
+	return ret, nil
+}
+
+func flattenConfigValues(target map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if nested, ok := normalizeConfigValue(value).(map[string]interface{}); ok {
+			flattenConfigValues(target, key, nested)
+		} else {
+			target[key] = value
+		}
+	}
+}
+
+// configValueToStrings converts the YAML value into option values, lists are used as multiple values
+// (lists of objects, eg. for kube.drain.stages, are passed as JSON)
+func configValueToStrings(value interface{}) ([]string, error) {
+	switch v := normalizeConfigValue(value).(type) {
+	case nil:
+		return []string{}, nil
+	case []interface{}:
+		ret := []string{}
+		for _, row := range v {
+			switch row.(type) {
+			case map[string]interface{}, []interface{}:
+				jsonValue, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				return []string{string(jsonValue)}, nil
+			}
+			ret = append(ret, fmt.Sprintf("%v", row))
+		}
+		return ret, nil
+	default:
+		return []string{fmt.Sprintf("%v", v)}, nil
+	}
+}
+
+// normalizeConfigValue converts YAML maps (map[interface{}]interface{}) and TOML tables into map[string]interface{} and []interface{}
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		ret := map[string]interface{}{}
+		for key, val := range v {
+			ret[fmt.Sprintf("%v", key)] = normalizeConfigValue(val)
+		}
+		return ret
+	case map[string]interface{}:
+		ret := map[string]interface{}{}
+		for key, val := range v {
+			ret[key] = normalizeConfigValue(val)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, val := range v {
+			ret[i] = normalizeConfigValue(val)
+		}
+		return ret
+	case []map[string]interface{}:
+		ret := make([]interface{}, len(v))
+		for i, val := range v {
+			ret[i] = normalizeConfigValue(val)
+		}
+		return ret
+	default:
+		return v
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+const testConfigFile = `
+scrape:
+  time: 2m
+drain:
+  enable: true
+  not-before: 10m
+  events:
+    - reboot
+    - freeze
+notification:
+  - slack://tokenA/tokenB/tokenC
+`
+
+func writeTestConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unable to write config file: %v", err)
+	}
+	return path
+}
+
+// parseTestArgs parses the arguments and the config file like main (precedence: arguments > env vars > config file)
+func parseTestArgs(t *testing.T, path string, args []string) ([]string, Opts) {
+	t.Helper()
+
+	opts := Opts{}
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	if _, err := parser.ParseArgs(args); err != nil {
+		t.Fatalf("unable to parse arguments: %v", err)
+	}
+
+	fileArgs, err := FileArgs(path, parser)
+	if err != nil {
+		t.Fatalf("unable to read config file: %v", err)
+	}
+
+	opts = Opts{}
+	parser = flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	if _, err := parser.ParseArgs(append(append([]string{}, fileArgs...), args...)); err != nil {
+		t.Fatalf("unable to parse arguments with config file: %v", err)
+	}
+
+	return fileArgs, opts
+}
+
+func TestFileArgs(t *testing.T) {
+	path := writeTestConfigFile(t, "config.yaml", testConfigFile)
+	fileArgs, opts := parseTestArgs(t, path, []string{})
+
+	expectedArgs := []string{
+		"--drain.enable",
+		"--drain.events=reboot",
+		"--drain.events=freeze",
+		"--drain.not-before=10m",
+		"--notification=slack://tokenA/tokenB/tokenC",
+		"--scrape.time=2m",
+	}
+	if !reflect.DeepEqual(fileArgs, expectedArgs) {
+		t.Errorf("expected file args %v, got %v", expectedArgs, fileArgs)
+	}
+
+	if opts.Scrape.Time != 2*time.Minute {
+		t.Errorf("expected scrape time 2m from file, got %v", opts.Scrape.Time)
+	}
+	if !opts.Drain.Enable {
+		t.Error("expected drain to be enabled from file")
+	}
+	if !reflect.DeepEqual(opts.Drain.Events, []string{"reboot", "freeze"}) {
+		t.Errorf("expected drain events from file, got %v", opts.Drain.Events)
+	}
+}
+
+func TestFileArgsPrecedence(t *testing.T) {
+	path := writeTestConfigFile(t, "config.yaml", testConfigFile)
+
+	tests := []struct {
+		name              string
+		env               map[string]string
+		args              []string
+		expectedScrape    time.Duration
+		expectedNotBefore time.Duration
+		skippedArg        string
+	}{
+		{
+			name:              "file",
+			expectedScrape:    2 * time.Minute,
+			expectedNotBefore: 10 * time.Minute,
+		},
+		{
+			name:              "env over file",
+			env:               map[string]string{"SCRAPE_TIME": "3m"},
+			expectedScrape:    3 * time.Minute,
+			expectedNotBefore: 10 * time.Minute,
+			skippedArg:        "--scrape.time=2m",
+		},
+		{
+			name:              "flag over env and file",
+			env:               map[string]string{"SCRAPE_TIME": "3m"},
+			args:              []string{"--scrape.time=4m"},
+			expectedScrape:    4 * time.Minute,
+			expectedNotBefore: 10 * time.Minute,
+			skippedArg:        "--scrape.time=2m",
+		},
+		{
+			name:              "flag with default value over file",
+			args:              []string{"--drain.not-before=5m"},
+			expectedScrape:    2 * time.Minute,
+			expectedNotBefore: 5 * time.Minute,
+			skippedArg:        "--drain.not-before=10m",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			fileArgs, opts := parseTestArgs(t, path, test.args)
+
+			if opts.Scrape.Time != test.expectedScrape {
+				t.Errorf("expected scrape time %v, got %v", test.expectedScrape, opts.Scrape.Time)
+			}
+			if opts.Drain.NotBefore != test.expectedNotBefore {
+				t.Errorf("expected drain not-before %v, got %v", test.expectedNotBefore, opts.Drain.NotBefore)
+			}
+
+			// options set via arguments or env vars are skipped
+			if test.skippedArg != "" && slices.Contains(fileArgs, test.skippedArg) {
+				t.Errorf("expected %v to be skipped, got %v", test.skippedArg, fileArgs)
+			}
+		})
+	}
+}
+
+func TestFileArgsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown option", "drain.unknown: true\n"},
+		{"config option", "config.watch: true\n"},
+		{"invalid bool", "drain.enable: maybe\n"},
+		{"invalid yaml", "drain: [\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeTestConfigFile(t, "config.yaml", test.content)
+
+			opts := Opts{}
+			parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+			if _, err := parser.ParseArgs([]string{}); err != nil {
+				t.Fatalf("unable to parse arguments: %v", err)
+			}
+
+			if _, err := FileArgs(path, parser); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
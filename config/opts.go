@@ -27,56 +27,205 @@ type (
 			Delay time.Duration `long:"startup.delay"   env:"STARTUP_DELAY"   description:"Delay startup time"  default:"30s"`
 		}
 
+		Config struct {
+			File          string        `long:"config"                 env:"CONFIG"                 description:"Config file (YAML, option names as keys, eg. \"drain.not-before: 10m\") for options not set via arguments or env vars"`
+			Watch         bool          `long:"config.watch"           env:"CONFIG_WATCH"           description:"Watch config file and apply changes without restart (eg. for config files mounted from ConfigMaps)"`
+			WatchInterval time.Duration `long:"config.watch.interval"  env:"CONFIG_WATCH_INTERVAL"  description:"Check interval for changes of config file" default:"10s"`
+		}
+
+		Oneshot struct {
+			Enable    bool   `long:"oneshot"             env:"ONESHOT"             description:"Run exactly one scrape without http server and startup delay, print decisions as JSON and exit (exit code 0: no ScheduledEvent handled, 1: error, 2: ScheduledEvent handled), eg. for systemd timers or cron"`
+			StateFile string `long:"oneshot.state-file"  env:"ONESHOT_STATE_FILE"  description:"State file for oneshot mode (eg. drained state of node between runs)" default:"/var/lib/azure-scheduledevents-manager/state.json"`
+		}
+
+		Systemd struct {
+			WatchdogScrapeTimeout time.Duration `long:"systemd.watchdog.scrape-timeout"  env:"SYSTEMD_WATCHDOG_SCRAPE_TIMEOUT"  description:"Max duration of a scrape (including drain) until WATCHDOG=1 is no longer sent to systemd (systemd restarts the hanging manager after WatchdogSec) and the heartbeat Lease is no longer renewed (0 = 3 x scrape.time + drain.not-before + drain.wait-before-cmd + drain.wait-after-cmd)" default:"0"`
+		}
+
 		Scrape struct {
 			Time time.Duration `long:"scrape.time"   env:"SCRAPE_TIME"   description:"Scrape time"  default:"1m"`
 		}
 
 		// Api option
 		Azure struct {
-			InstanceApiUrl        string        `long:"azure.metadatainstance-url"    env:"AZURE_METADATAINSTANCE_URL"    description:"Azure ScheduledEvents API URL" default:"http://169.254.169.254/metadata/instance?api-version=2019-08-01"`
-			ScheduledEventsApiUrl string        `long:"azure.scheduledevents-url"     env:"AZURE_SCHEDULEDEVENTS_URL"     description:"Azure ScheduledEvents API URL" default:"http://169.254.169.254/metadata/scheduledevents?api-version=2019-08-01"`
+			InstanceApiUrl        string        `long:"azure.metadatainstance-url"    env:"AZURE_METADATAINSTANCE_URL"    description:"Azure ScheduledEvents API URL" default:"http://169.254.169.254/metadata/instance?api-version=2019-08-01" redact:"userinfo"`
+			ScheduledEventsApiUrl string        `long:"azure.scheduledevents-url"     env:"AZURE_SCHEDULEDEVENTS_URL"     description:"Azure ScheduledEvents API URL" default:"http://169.254.169.254/metadata/scheduledevents?api-version=2019-08-01" redact:"userinfo"`
 			Timeout               time.Duration `long:"azure.timeout"                 env:"AZURE_TIMEOUT"                 description:"Azure API timeout (seconds)"   default:"30s"`
 			ErrorThreshold        int           `long:"azure.error-threshold"         env:"AZURE_ERROR_THRESHOLD"         description:"Azure API error threshold (after which app will panic)"   default:"0"`
 			ApproveScheduledEvent bool          `long:"azure.approve-scheduledevent"  env:"AZURE_APPROVE_SCHEDULEDEVENT"  description:"Approve ScheduledEvent and start (if possible) start them ASAP"`
 		}
 
 		Instance struct {
-			VmNodeName string `long:"vm.nodename"    env:"VM_NODENAME"     description:"VM node name"`
+			VmNodeName     string   `long:"vm.nodename"           env:"VM_NODENAME"                          description:"VM node name"`
+			Aliases        []string `long:"vm.nodename.alias"     env:"VM_NODENAME_ALIAS"    env-delim:" "   description:"Additional names of the VM for matching ScheduledEvent resources"`
+			Regexp         []string `long:"vm.nodename.regexp"    env:"VM_NODENAME_REGEXP"   env-delim:" "   description:"Regular expressions for matching ScheduledEvent resources of the VM"`
+			EmptyResources string   `long:"vm.empty-resources"    env:"VM_EMPTY_RESOURCES"                   description:"Policy for ScheduledEvents without resources (ignore: only metrics, match: handle as event for the VM)" choice:"ignore" choice:"match" default:"ignore"` //nolint:staticcheck
 		}
 
 		Drain struct {
 			Enable    bool          `long:"drain.enable"             env:"DRAIN_ENABLE"                description:"Enable drain handling"`
-			Mode      string        `long:"drain.mode"               env:"DRAIN_MODE"                  description:"Mode" choice:"kubernetes" choice:"command"` //nolint:golint,staticcheck
+			Mode      string        `long:"drain.mode"               env:"DRAIN_MODE"                  description:"Mode" choice:"kubernetes" choice:"nodemaintenance" choice:"command"` //nolint:golint,staticcheck
 			NotBefore time.Duration `long:"drain.not-before"         env:"DRAIN_NOT_BEFORE"            description:"Dont drain before this time" default:"5m"`
 			Events    []string      `long:"drain.events"             env:"DRAIN_EVENTS" env-delim:" "  description:"Enable drain handling" default:"reboot" default:"redeploy" default:"preempt" default:"terminate"` //nolint:staticcheck
 
 			WaitBeforeCmd time.Duration `long:"drain.wait-before-cmd"  env:"DRAIN_WAIT_BEFORE_CMD"     description:"Wait duration before trigger drain command" default:"0"`
 			WaitAfterCmd  time.Duration `long:"drain.wait-after-cmd"   env:"DRAIN_WAIT_AFTER_CMD"      description:"Wait duration before trigger drain command" default:"0"`
+
+			Preempt struct {
+				FastPath     bool          `long:"drain.preempt.fast-path"      env:"DRAIN_PREEMPT_FAST_PATH"                     description:"Enable fast path for Preempt ScheduledEvents (no delays, immediate taint, cordon and eviction, no approval)"`
+				ScrapeTime   time.Duration `long:"drain.preempt.scrape-time"    env:"DRAIN_PREEMPT_SCRAPE_TIME"                   description:"Scrape time for Preempt ScheduledEvents in fast path" default:"5s"`
+				GracePeriod  time.Duration `long:"drain.preempt.grace-period"   env:"DRAIN_PREEMPT_GRACE_PERIOD"                  description:"Eviction grace period in fast path" default:"10s"`
+				Notification []string      `long:"drain.preempt.notification"   env:"DRAIN_PREEMPT_NOTIFICATION"   env-delim:" "  description:"Shoutrrr url for high priority notifications in fast path (default: --notification)" redact:"true"`
+			}
+
+			Taint struct {
+				Enable    bool          `long:"drain.taint.enable"      env:"DRAIN_TAINT_ENABLE"      description:"Enable taint (PreferNoSchedule) stage before drain"`
+				NotBefore time.Duration `long:"drain.taint.not-before"  env:"DRAIN_TAINT_NOT_BEFORE"  description:"Dont taint before this time (0 = as soon as ScheduledEvent is detected)" default:"0"`
+			}
+
+			Cordon struct {
+				Enable    bool          `long:"drain.cordon.enable"      env:"DRAIN_CORDON_ENABLE"      description:"Enable cordon stage before drain"`
+				NotBefore time.Duration `long:"drain.cordon.not-before"  env:"DRAIN_CORDON_NOT_BEFORE"  description:"Dont cordon before this time" default:"10m"`
+			}
+
+			Terminate struct {
+				Enable bool   `long:"drain.terminate.enable"  env:"DRAIN_TERMINATE_ENABLE"  description:"Enable node cleanup after drain and approval of Terminate ScheduledEvents (node is not uncordoned afterwards)"`
+				Cmd    string `long:"drain.terminate.cmd"     env:"DRAIN_TERMINATE_CMD"     description:"Deregistration command executed after drain and approval of Terminate ScheduledEvents" redact:"true"`
+			}
 		}
 
 		Command struct {
 			Test struct {
-				Cmd string `long:"command.test.cmd"  env:"COMMAND_TEST_CMD"   description:"Test command in command mode"`
+				Cmd string `long:"command.test.cmd"  env:"COMMAND_TEST_CMD"   description:"Test command in command mode" redact:"true"`
+			}
+			Taint struct {
+				Cmd string `long:"command.taint.cmd"  env:"COMMAND_TAINT_CMD"   description:"Taint command in command mode" redact:"true"`
+			}
+			Cordon struct {
+				Cmd string `long:"command.cordon.cmd"  env:"COMMAND_CORDON_CMD"   description:"Cordon command in command mode" redact:"true"`
 			}
 			Drain struct {
-				Cmd string `long:"command.drain.cmd"  env:"COMMAND_DRAIN_CMD"   description:"Drain command in command mode"`
+				Cmd string `long:"command.drain.cmd"  env:"COMMAND_DRAIN_CMD"   description:"Drain command in command mode" redact:"true"`
+			}
+			Preempt struct {
+				Cmd string `long:"command.preempt.cmd"  env:"COMMAND_PREEMPT_CMD"   description:"Preempt command in command mode for fast path (default: drain command)" redact:"true"`
 			}
 			Uncordon struct {
-				Cmd string `long:"command.uncordon.cmd"  env:"COMMAND_UNCORDON_CMD"   description:"Uncordon command in command mode"`
+				Cmd string `long:"command.uncordon.cmd"  env:"COMMAND_UNCORDON_CMD"   description:"Uncordon command in command mode" redact:"true"`
 			}
 		}
 
 		Kubernetes struct {
-			NodeName string `long:"kube.nodename"  env:"KUBE_NODENAME"   description:"Kubernetes node name"`
+			NodeName string `long:"kube.nodename"  env:"KUBE_NODENAME"   description:"Kubernetes node name (discovered via spec.providerID if empty)"`
 
 			Drain struct {
-				Args   []string `long:"kube.drain.args"     env:"KUBE_DRAIN_ARGS"     description:"Arguments for kubectl drain" env-delim:" "`
+				Args   []string `long:"kube.drain.args"     env:"KUBE_DRAIN_ARGS"     description:"Arguments for kubectl drain" env-delim:" " redact:"userinfo"`
 				DryRun bool     `long:"kube.drain.dry-run"  env:"KUBE_DRAIN_DRY_RUN"  description:"Do not drain, uncordon or label any node"`
+
+				Stages KubeDrainStageList `long:"kube.drain.stages"  env:"KUBE_DRAIN_STAGES"  description:"Eviction stages (JSON list) executed before kubectl drain"`
+
+				GracePeriod struct {
+					Dynamic bool          `long:"kube.drain.grace-period.dynamic"  env:"KUBE_DRAIN_GRACE_PERIOD_DYNAMIC"  description:"Calculate eviction grace period per pod from remaining time until NotBefore (capped by terminationGracePeriodSeconds of pod)"`
+					Margin  time.Duration `long:"kube.drain.grace-period.margin"   env:"KUBE_DRAIN_GRACE_PERIOD_MARGIN"   description:"Safety margin subtracted from remaining time until NotBefore for dynamic grace period" default:"30s"`
+				}
+
+				Hooks struct {
+					Enable  bool          `long:"kube.drain.hooks.enable"   env:"KUBE_DRAIN_HOOKS_ENABLE"   description:"Call pre-eviction hooks of pods (declared via pod annotations) before eviction"`
+					Timeout time.Duration `long:"kube.drain.hooks.timeout"  env:"KUBE_DRAIN_HOOKS_TIMEOUT"  description:"Default and max timeout for pre-eviction hooks (pod annotation can only lower it)" default:"30s"`
+					Margin  time.Duration `long:"kube.drain.hooks.margin"   env:"KUBE_DRAIN_HOOKS_MARGIN"   description:"Stop pre-eviction hooks this duration before NotBefore of ScheduledEvent (time left for eviction)" default:"2m"`
+				}
+
+				VolumeDetach struct {
+					Enable  bool          `long:"kube.drain.wait-volume-detach"          env:"KUBE_DRAIN_WAIT_VOLUME_DETACH"          description:"Wait until all VolumeAttachments of the node are gone after drain"`
+					Margin  time.Duration `long:"kube.drain.wait-volume-detach.margin"   env:"KUBE_DRAIN_WAIT_VOLUME_DETACH_MARGIN"   description:"Stop waiting for volume detach this duration before NotBefore of ScheduledEvent" default:"30s"`
+					Timeout time.Duration `long:"kube.drain.wait-volume-detach.timeout"  env:"KUBE_DRAIN_WAIT_VOLUME_DETACH_TIMEOUT"  description:"Max wait time for volume detach if ScheduledEvent has no NotBefore" default:"2m"`
+				}
+
+				Report struct {
+					Enable    bool          `long:"kube.drain.report"            env:"KUBE_DRAIN_REPORT"            description:"Record drain reports (JSON and Markdown) with pod placement before and after drain as ConfigMaps"`
+					Namespace string        `long:"kube.drain.report.namespace"  env:"KUBE_DRAIN_REPORT_NAMESPACE"  description:"Namespace for drain report ConfigMaps" default:"kube-system"`
+					Wait      time.Duration `long:"kube.drain.report.wait"       env:"KUBE_DRAIN_REPORT_WAIT"       description:"Max wait time (after drain) for ready replacements of evicted pods before drain report is finished" default:"5m"`
+					Retention time.Duration `long:"kube.drain.report.retention"  env:"KUBE_DRAIN_REPORT_RETENTION"  description:"Retention of finished drain reports" default:"168h"`
+				}
+			}
+
+			Capacity struct {
+				Check         bool `long:"kube.capacity.check"  env:"KUBE_CAPACITY_CHECK"  description:"Check if remaining schedulable nodes have enough allocatable cpu and memory for the pods of the node before drain"`
+				Overprovision struct {
+					Enable        bool          `long:"kube.capacity.overprovision"                env:"KUBE_CAPACITY_OVERPROVISION"                description:"Create placeholder pods if capacity is insufficient to trigger cluster autoscaler scale up before drain"`
+					Namespace     string        `long:"kube.capacity.overprovision.namespace"      env:"KUBE_CAPACITY_OVERPROVISION_NAMESPACE"      description:"Namespace for placeholder pods" default:"kube-system"`
+					Image         string        `long:"kube.capacity.overprovision.image"          env:"KUBE_CAPACITY_OVERPROVISION_IMAGE"          description:"Image for placeholder pods" default:"registry.k8s.io/pause:3.10"`
+					PriorityClass string        `long:"kube.capacity.overprovision.priorityclass"  env:"KUBE_CAPACITY_OVERPROVISION_PRIORITYCLASS"  description:"PriorityClass for placeholder pods"`
+					Timeout       time.Duration `long:"kube.capacity.overprovision.timeout"        env:"KUBE_CAPACITY_OVERPROVISION_TIMEOUT"        description:"Max wait time until placeholder pods are scheduled (0 = dont wait)" default:"5m"`
+					Margin        time.Duration `long:"kube.capacity.overprovision.margin"         env:"KUBE_CAPACITY_OVERPROVISION_MARGIN"         description:"Stop waiting for placeholder pods this duration before NotBefore of ScheduledEvent (time left for eviction)" default:"5m"`
+				}
+			}
+
+			Autoscaler struct {
+				ScaleDownDisabled struct {
+					Self  bool   `long:"kube.autoscaler.scale-down-disabled.self"   env:"KUBE_AUTOSCALER_SCALE_DOWN_DISABLED_SELF"   description:"Disable cluster autoscaler scale down of the node during maintenance"`
+					Peers string `long:"kube.autoscaler.scale-down-disabled.peers"  env:"KUBE_AUTOSCALER_SCALE_DOWN_DISABLED_PEERS"  description:"Label selector for peer nodes which should not be scaled down by cluster autoscaler during maintenance"`
+				}
+			}
+
+			Pods struct {
+				Annotate      bool   `long:"kube.pods.annotate"        env:"KUBE_PODS_ANNOTATE"        description:"Annotate pods of the node with EventId, EventType and NotBefore when ScheduledEvent is detected"`
+				ReadinessGate string `long:"kube.pods.readiness-gate"  env:"KUBE_PODS_READINESS_GATE"  description:"Pod readiness gate condition type which is set to False at drain time (only pods with this readiness gate)"`
+			}
+
+			LoadBalancer struct {
+				Exclude    bool          `long:"kube.loadbalancer.exclude"      env:"KUBE_LOADBALANCER_EXCLUDE"      description:"Exclude node from external load balancers (node.kubernetes.io/exclude-from-external-load-balancers) before eviction"`
+				SettleTime time.Duration `long:"kube.loadbalancer.settle-time"  env:"KUBE_LOADBALANCER_SETTLE_TIME"  description:"Wait time after excluding node from external load balancers before eviction" default:"30s"`
+			}
+
+			Kured struct {
+				Mode       string        `long:"kube.kured.mode"        env:"KUBE_KURED_MODE"        description:"Interoperability with kured reboot lock (respect: wait until lock is free, acquire: wait and hold lock during maintenance)" choice:"" choice:"respect" choice:"acquire"` //nolint:staticcheck
+				Namespace  string        `long:"kube.kured.namespace"   env:"KUBE_KURED_NAMESPACE"   description:"Namespace of kured DaemonSet" default:"kube-system"`
+				DaemonSet  string        `long:"kube.kured.daemonset"   env:"KUBE_KURED_DAEMONSET"   description:"Name of kured DaemonSet" default:"kured"`
+				Annotation string        `long:"kube.kured.annotation"  env:"KUBE_KURED_ANNOTATION"  description:"Lock annotation of kured DaemonSet" default:"weave.works/kured-node-lock"`
+				Timeout    time.Duration `long:"kube.kured.timeout"     env:"KUBE_KURED_TIMEOUT"     description:"Max wait time for kured lock if ScheduledEvent has no NotBefore" default:"10m"`
+				Margin     time.Duration `long:"kube.kured.margin"      env:"KUBE_KURED_MARGIN"      description:"Stop waiting for kured lock this duration before NotBefore of ScheduledEvent (time left for drain)" default:"5m"`
+			}
+
+			Pause struct {
+				ConfigMap  string `long:"kube.pause.configmap"   env:"KUBE_PAUSE_CONFIGMAP"   description:"ConfigMap (namespace/name) with cluster-wide pause switch (empty = disabled)"`
+				Key        string `long:"kube.pause.key"         env:"KUBE_PAUSE_KEY"         description:"Key of pause switch in ConfigMap" default:"paused"`
+				Annotation string `long:"kube.pause.annotation"  env:"KUBE_PAUSE_ANNOTATION"  description:"Node annotation with pause switch (empty = disabled)" default:"scheduledevents-manager/paused"`
+			}
+
+			ScheduledEvents struct {
+				Enable         bool          `long:"kube.scheduledevents.enable"           env:"KUBE_SCHEDULEDEVENTS_ENABLE"           description:"Create ScheduledEvent custom resources (cluster-scoped) for ScheduledEvents of the node"`
+				ManualApproval bool          `long:"kube.scheduledevents.manual-approval"  env:"KUBE_SCHEDULEDEVENTS_MANUAL_APPROVAL"  description:"Approve ScheduledEvent only after spec.approved of the ScheduledEvent resource is set to true"`
+				Retention      time.Duration `long:"kube.scheduledevents.retention"        env:"KUBE_SCHEDULEDEVENTS_RETENTION"        description:"Retention of completed ScheduledEvent resources" default:"24h"`
+			}
+
+			Heartbeat struct {
+				Enable    bool   `long:"kube.heartbeat.enable"     env:"KUBE_HEARTBEAT_ENABLE"     description:"Renew a Lease per node with version, last IMDS success and state of the manager"`
+				Namespace string `long:"kube.heartbeat.namespace"  env:"KUBE_HEARTBEAT_NAMESPACE"  description:"Namespace for heartbeat Leases" default:"kube-system"`
+			}
+
+			Events struct {
+				Enable    bool   `long:"kube.events.enable"     env:"KUBE_EVENTS_ENABLE"     description:"Record Kubernetes Events for ScheduledEvents on the node"`
+				Namespace string `long:"kube.events.namespace"  env:"KUBE_EVENTS_NAMESPACE"  description:"Namespace for Kubernetes Events" default:"default"`
+			}
+
+			Terminate struct {
+				DeleteNode bool   `long:"kube.terminate.delete-node"  env:"KUBE_TERMINATE_DELETE_NODE"  description:"Delete Kubernetes node object after drain and approval of Terminate ScheduledEvents"`
+				Taint      string `long:"kube.terminate.taint"        env:"KUBE_TERMINATE_TAINT"        description:"Taint (key=value:effect) for node after drain and approval of Terminate ScheduledEvents (eg. node.kubernetes.io/out-of-service=nodeshutdown:NoExecute)"`
+			}
+
+			NodeMaintenance struct {
+				Timeout time.Duration `long:"kube.nodemaintenance.timeout"  env:"KUBE_NODEMAINTENANCE_TIMEOUT"  description:"Max wait time for NodeMaintenance in nodemaintenance mode if ScheduledEvent has no NotBefore" default:"10m"`
+			}
+
+			NodeCondition struct {
+				Enable bool   `long:"kube.nodecondition.enable"  env:"KUBE_NODECONDITION_ENABLE"  description:"Set node condition for ScheduledEvents"`
+				Type   string `long:"kube.nodecondition.type"    env:"KUBE_NODECONDITION_TYPE"    description:"Type of node condition" default:"AzureScheduledMaintenance"`
 			}
 		}
 
 		Notification struct {
-			List        []string `long:"notification"                 env:"NOTIFICATION"              description:"Shoutrrr url for notifications (https://containrrr.github.io/shoutrrr/)" env-delim:" "`
+			List        []string `long:"notification"                 env:"NOTIFICATION"              description:"Shoutrrr url for notifications (https://containrrr.github.io/shoutrrr/)" env-delim:" " redact:"true"`
 			MsgTemplate string   `long:"notification.messagetemplate" env:"NOTIFICATION_MESSAGE_TEMPLATE"  description:"Notification template" default:"%v"`
 		}
 
@@ -86,8 +235,20 @@ type (
 	}
 )
 
+type (
+	FleetStatusOpts struct {
+		Selector string `long:"selector"  description:"Label selector for nodes which should run the manager"`
+		Output   string `long:"output"    description:"Output format" choice:"table" choice:"json" default:"table"` //nolint:staticcheck
+	}
+
+	ConfigDumpOpts struct {
+		Output string `long:"output"  description:"Output format" choice:"yaml" choice:"json" default:"yaml"` //nolint:staticcheck
+	}
+)
+
+// GetJson returns the config as JSON with redacted secrets
 func (o *Opts) GetJson() []byte {
-	jsonBytes, err := json.Marshal(o)
+	jsonBytes, err := json.Marshal(o.Redacted())
 	if err != nil {
 		panic(err)
 	}
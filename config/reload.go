@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+)
+
+// KeepRestartOptions keeps the current value of options which are only used at startup
+// (eg. server, logger, metadata client and Kubernetes drain manager) and returns the names of changed
+// options which are ignored until restart, commands of the command mode are passed to the drain manager
+func (o *Opts) KeepRestartOptions(current Opts) []string {
+	ignored := []string{}
+
+	// discovered at startup if not set
+	if o.Instance.VmNodeName == "" {
+		o.Instance.VmNodeName = current.Instance.VmNodeName
+	}
+	if o.Kubernetes.NodeName == "" {
+		o.Kubernetes.NodeName = current.Kubernetes.NodeName
+	}
+
+	keepOption(&ignored, "log.*", &o.Logger, current.Logger)
+	keepOption(&ignored, "server.*", &o.Server, current.Server)
+	keepOption(&ignored, "startup.*", &o.Startup, current.Startup)
+	keepOption(&ignored, "config.*", &o.Config, current.Config)
+	keepOption(&ignored, "oneshot.*", &o.Oneshot, current.Oneshot)
+	keepOption(&ignored, "azure.metadatainstance-url", &o.Azure.InstanceApiUrl, current.Azure.InstanceApiUrl)
+	keepOption(&ignored, "azure.scheduledevents-url", &o.Azure.ScheduledEventsApiUrl, current.Azure.ScheduledEventsApiUrl)
+	keepOption(&ignored, "azure.timeout", &o.Azure.Timeout, current.Azure.Timeout)
+	keepOption(&ignored, "vm.nodename", &o.Instance.VmNodeName, current.Instance.VmNodeName)
+	keepOption(&ignored, "drain.enable", &o.Drain.Enable, current.Drain.Enable)
+	keepOption(&ignored, "drain.mode", &o.Drain.Mode, current.Drain.Mode)
+	keepOption(&ignored, "drain.preempt.fast-path", &o.Drain.Preempt.FastPath, current.Drain.Preempt.FastPath)
+	keepOption(&ignored, "drain.preempt.scrape-time", &o.Drain.Preempt.ScrapeTime, current.Drain.Preempt.ScrapeTime)
+	keepOption(&ignored, "drain.preempt.grace-period", &o.Drain.Preempt.GracePeriod, current.Drain.Preempt.GracePeriod)
+	keepOption(&ignored, "drain.terminate.cmd", &o.Drain.Terminate.Cmd, current.Drain.Terminate.Cmd)
+	keepOption(&ignored, "kube.*", &o.Kubernetes, current.Kubernetes)
+
+	return ignored
+}
+
+func keepOption[T any](ignored *[]string, name string, target *T, current T) {
+	if !reflect.DeepEqual(*target, current) {
+		*ignored = append(*ignored, name)
+		*target = current
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containrrr/shoutrrr/pkg/router"
+)
+
+// Validate checks the options which cannot be validated by the argparser, returns all found problems
+func (o *Opts) Validate() error {
+	errs := []error{}
+
+	// validate instanceUrl url
+	if err := validateApiUrl(o.Azure.InstanceApiUrl); err != nil {
+		errs = append(errs, err)
+	}
+
+	// validate scheduledEventsUrl url
+	if err := validateApiUrl(o.Azure.ScheduledEventsApiUrl); err != nil {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, o.validateDurations()...)
+	errs = append(errs, o.validateDrainMode()...)
+	errs = append(errs, o.validateNotification()...)
+
+	return errors.Join(errs...)
+}
+
+// ValidateCommands checks if the commands used by the drain mode are available
+func (o *Opts) ValidateCommands() error {
+	if !o.Drain.Enable {
+		return nil
+	}
+
+	errs := []error{}
+	switch o.Drain.Mode {
+	case "kubernetes", "nodemaintenance":
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			errs = append(errs, fmt.Errorf(`drain mode "%v" requires kubectl: %w`, o.Drain.Mode, err))
+		}
+	}
+
+	commands := map[string]string{
+		"drain.terminate.cmd": o.Drain.Terminate.Cmd,
+	}
+	if o.Drain.Mode == "command" {
+		commands["command.test.cmd"] = o.Command.Test.Cmd
+		commands["command.taint.cmd"] = o.Command.Taint.Cmd
+		commands["command.cordon.cmd"] = o.Command.Cordon.Cmd
+		commands["command.drain.cmd"] = o.Command.Drain.Cmd
+		commands["command.preempt.cmd"] = o.Command.Preempt.Cmd
+		commands["command.uncordon.cmd"] = o.Command.Uncordon.Cmd
+	}
+
+	options := []string{}
+	for option, command := range commands {
+		if command != "" {
+			options = append(options, option)
+		}
+	}
+	sort.Strings(options)
+
+	if len(options) > 0 {
+		// commands are executed via shell
+		if _, err := exec.LookPath("sh"); err != nil {
+			errs = append(errs, fmt.Errorf(`commands require sh: %w`, err))
+		}
+	}
+
+	for _, option := range options {
+		if executable := commandExecutable(commands[option]); executable != "" {
+			if _, err := exec.LookPath(executable); err != nil {
+				errs = append(errs, fmt.Errorf(`command "%v" of --%v not found: %w`, executable, option, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (o *Opts) validateDurations() []error {
+	errs := []error{}
+
+	names := []string{}
+	fields := o.optionFields()
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if duration, ok := fields[name].Interface().(time.Duration); ok && duration < 0 {
+			errs = append(errs, fmt.Errorf(`--%v must not be negative, got %v`, name, duration))
+		}
+	}
+
+	if o.Scrape.Time <= 0 {
+		errs = append(errs, fmt.Errorf(`--scrape.time must be greater than zero, got %v`, o.Scrape.Time))
+	}
+
+	if o.Azure.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf(`--azure.timeout must be greater than zero, got %v`, o.Azure.Timeout))
+	}
+
+	if o.Drain.Preempt.FastPath && o.Drain.Preempt.ScrapeTime <= 0 {
+		errs = append(errs, fmt.Errorf(`--drain.preempt.scrape-time must be greater than zero, got %v`, o.Drain.Preempt.ScrapeTime))
+	}
+
+	if o.Config.Watch && o.Config.WatchInterval <= 0 {
+		errs = append(errs, fmt.Errorf(`--config.watch.interval must be greater than zero, got %v`, o.Config.WatchInterval))
+	}
+
+	return errs
+}
+
+func (o *Opts) validateDrainMode() []error {
+	errs := []error{}
+
+	if !o.Drain.Enable {
+		return errs
+	}
+
+	switch o.Drain.Mode {
+	case "kubernetes", "nodemaintenance":
+		if o.Kubernetes.Pause.ConfigMap != "" {
+			if namespace, name, found := strings.Cut(o.Kubernetes.Pause.ConfigMap, "/"); !found || namespace == "" || name == "" {
+				errs = append(errs, fmt.Errorf(`--kube.pause.configmap must be namespace/name, got "%v"`, o.Kubernetes.Pause.ConfigMap))
+			}
+		}
+
+		if o.Kubernetes.ScheduledEvents.ManualApproval {
+			if !o.Kubernetes.ScheduledEvents.Enable {
+				errs = append(errs, fmt.Errorf(`--kube.scheduledevents.manual-approval requires --kube.scheduledevents.enable`))
+			}
+
+			if !o.Azure.ApproveScheduledEvent {
+				errs = append(errs, fmt.Errorf(`--kube.scheduledevents.manual-approval requires --azure.approve-scheduledevent`))
+			}
+		}
+	case "command":
+		if o.Command.Drain.Cmd == "" {
+			errs = append(errs, fmt.Errorf(`drain mode "command" requires --command.drain.cmd`))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("drain enabled but no drain mode set"))
+	}
+
+	return errs
+}
+
+func (o *Opts) validateNotification() []error {
+	errs := []error{}
+
+	// message template is used with fmt.Sprintf and the message as only argument
+	if message := fmt.Sprintf(o.Notification.MsgTemplate, "message"); strings.Contains(message, "%!") {
+		errs = append(errs, fmt.Errorf(`invalid --notification.messagetemplate "%v" (must contain exactly one %%v for the message): %v`, o.Notification.MsgTemplate, message))
+	}
+
+	urlList := map[string][]string{
+		"notification":               o.Notification.List,
+		"drain.preempt.notification": o.Drain.Preempt.Notification,
+	}
+	for _, option := range []string{"notification", "drain.preempt.notification"} {
+		for _, notificationUrl := range urlList[option] {
+			if _, err := (&router.ServiceRouter{}).Locate(notificationUrl); err != nil {
+				// error message could contain the url with secrets
+				errs = append(errs, fmt.Errorf(`invalid --%v url "%v": %v`, option, RedactUrl(notificationUrl), redactError(err, notificationUrl)))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateApiUrl(apiUrl string) error {
+	parsedUrl, err := url.Parse(apiUrl)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(parsedUrl.Scheme) {
+	case "http":
+	case "https":
+	default:
+		return fmt.Errorf("ApiURL scheme not allowed (must be http or https), got %v", apiUrl)
+	}
+
+	return nil
+}
+
+// commandExecutable returns the executable of a shell command (skipping leading env var assignments)
+func commandExecutable(command string) string {
+	for _, field := range strings.Fields(command) {
+		if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+func redactError(err error, secret string) string {
+	return strings.ReplaceAll(err.Error(), secret, RedactUrl(secret))
+}
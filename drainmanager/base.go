@@ -2,6 +2,7 @@ package drainmanager
 
 import (
 	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/config"
 )
 
 type (
@@ -9,7 +10,30 @@ type (
 		SetInstanceName(name string)
 		InstanceName() string
 		Test() error
+		Taint(event *azuremetadata.AzureScheduledEvent) bool
+		Cordon(event *azuremetadata.AzureScheduledEvent) bool
 		Drain(event *azuremetadata.AzureScheduledEvent) bool
+		Preempt(event *azuremetadata.AzureScheduledEvent) bool
+		Terminate(event *azuremetadata.AzureScheduledEvent) bool
 		Uncordon() bool
+		DrainReport() string
+		Paused() (bool, string, error)
+
+		ScheduledEventDetected(event *azuremetadata.AzureScheduledEvent)
+		ScheduledEventApproved(event *azuremetadata.AzureScheduledEvent)
+		ApprovalGranted(event *azuremetadata.AzureScheduledEvent) bool
+		ScheduledEventCleared()
+
+		Heartbeat(status HeartbeatStatus)
+	}
+
+	// ConfigUpdater is implemented by drain managers which apply reloaded configs (eg. commands of command mode)
+	ConfigUpdater interface {
+		UpdateConfig(conf config.Opts)
+	}
+
+	// DrainCanceler is implemented by drain managers which can cancel a running drain (eg. for preempt fast path)
+	DrainCanceler interface {
+		CancelDrain()
 	}
 )
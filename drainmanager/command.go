@@ -1,13 +1,8 @@
 package drainmanager
 
 import (
-	"fmt"
-	"log/slog"
-	"os"
-	"os/exec"
-	"strings"
+	"sync"
 
-	slogio "github.com/utkuozdemir/go-slogio"
 	"github.com/webdevops/go-common/log/slogger"
 
 	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
@@ -19,6 +14,22 @@ type DrainManagerCommand struct {
 	Conf         config.Opts
 	Logger       *slogger.Logger
 	instanceName string
+
+	// Conf is replaced by reloaded configs (UpdateConfig)
+	confLock sync.RWMutex
+}
+
+// UpdateConfig applies a reloaded config, the commands are used by the next call
+func (m *DrainManagerCommand) UpdateConfig(conf config.Opts) {
+	m.confLock.Lock()
+	defer m.confLock.Unlock()
+	m.Conf = conf
+}
+
+func (m *DrainManagerCommand) config() config.Opts {
+	m.confLock.RLock()
+	defer m.confLock.RUnlock()
+	return m.Conf
 }
 
 func (m *DrainManagerCommand) SetInstanceName(name string) {
@@ -30,55 +41,75 @@ func (m *DrainManagerCommand) InstanceName() string {
 }
 
 func (m *DrainManagerCommand) Test() error {
-	if m.Conf.Command.Test.Cmd != "" {
-		m.exec(m.Conf.Command.Test.Cmd, nil)
+	if command := m.config().Command.Test.Cmd; command != "" {
+		m.exec(command, nil)
 	}
 
 	return nil
 }
 
+func (m *DrainManagerCommand) Taint(event *azuremetadata.AzureScheduledEvent) bool {
+	if command := m.config().Command.Taint.Cmd; command != "" {
+		return m.exec(command, event)
+	}
+	return true
+}
+
+func (m *DrainManagerCommand) Cordon(event *azuremetadata.AzureScheduledEvent) bool {
+	if command := m.config().Command.Cordon.Cmd; command != "" {
+		return m.exec(command, event)
+	}
+	return true
+}
+
 func (m *DrainManagerCommand) Drain(event *azuremetadata.AzureScheduledEvent) bool {
-	if m.Conf.Command.Drain.Cmd != "" {
-		return m.exec(m.Conf.Command.Drain.Cmd, event)
+	if command := m.config().Command.Drain.Cmd; command != "" {
+		return m.exec(command, event)
 	}
 	return true
 }
 
-func (m *DrainManagerCommand) Uncordon() bool {
-	if m.Conf.Command.Uncordon.Cmd != "" {
-		return m.exec(m.Conf.Command.Uncordon.Cmd, nil)
+func (m *DrainManagerCommand) Preempt(event *azuremetadata.AzureScheduledEvent) bool {
+	if command := m.config().Command.Preempt.Cmd; command != "" {
+		return m.exec(command, event)
+	}
+	return m.Drain(event)
+}
+
+func (m *DrainManagerCommand) Terminate(event *azuremetadata.AzureScheduledEvent) bool {
+	if command := m.config().Drain.Terminate.Cmd; command != "" {
+		return m.exec(command, event)
 	}
 	return true
 }
 
-func (m *DrainManagerCommand) exec(command string, event *azuremetadata.AzureScheduledEvent) bool {
-	env := os.Environ()
-	if event != nil {
-		env = append(env, fmt.Sprintf("EVENT_ID=%v", event.EventId))
-		env = append(env, fmt.Sprintf("EVENT_SOURCE=%v", event.EventSource))
-		env = append(env, fmt.Sprintf("EVENT_STATUS=%v", event.EventStatus))
-		env = append(env, fmt.Sprintf("EVENT_TYPE=%v", event.EventType))
-		env = append(env, fmt.Sprintf("EVENT_NOTBEFORE=%v", event.NotBefore))
-		env = append(env, fmt.Sprintf("EVENT_RESOURCES=%v", strings.Join(event.Resources, " ")))
-		env = append(env, fmt.Sprintf("EVENT_RESOURCETYPE=%v", event.ResourceType))
+func (m *DrainManagerCommand) Uncordon() bool {
+	if command := m.config().Command.Uncordon.Cmd; command != "" {
+		return m.exec(command, nil)
 	}
+	return true
+}
+
+func (m *DrainManagerCommand) DrainReport() string {
+	return ""
+}
 
-	cmd := exec.Command("sh", "-c", command)
-	cmd.Env = env
+func (m *DrainManagerCommand) exec(command string, event *azuremetadata.AzureScheduledEvent) bool {
+	return execShellCommand(m.Logger, command, event)
+}
 
-	cmdLogger := m.Logger.With(slog.String("command", "sh"))
-	writer := &slogio.Writer{Log: cmdLogger.Slog(), Level: slogger.LevelInfo}
-	defer writer.Close()
+func (m *DrainManagerCommand) Paused() (bool, string, error) {
+	return false, "", nil
+}
 
-	cmd.Stdout = writer
-	cmd.Stderr = writer
+func (m *DrainManagerCommand) ScheduledEventDetected(event *azuremetadata.AzureScheduledEvent) {}
 
-	m.Logger.Debugf("EXEC: %v", cmd.String())
-	err := cmd.Run()
-	if err != nil {
-		cmdLogger.Error(err.Error())
-		return false
-	}
+func (m *DrainManagerCommand) ScheduledEventApproved(event *azuremetadata.AzureScheduledEvent) {}
 
+func (m *DrainManagerCommand) ApprovalGranted(event *azuremetadata.AzureScheduledEvent) bool {
 	return true
 }
+
+func (m *DrainManagerCommand) ScheduledEventCleared() {}
+
+func (m *DrainManagerCommand) Heartbeat(status HeartbeatStatus) {}
@@ -0,0 +1,139 @@
+package drainmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	KubernetesAnnotationScaleDownDisabled        = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	KubernetesAnnotationScaleDownDisabledOwnedBy = "webdevops.io/azure-scheduledevents-manager.scale-down-disabled-by"
+
+	kubernetesAnnotationRetryCount = 3
+)
+
+// disableAutoscalerScaleDown annotates the node itself and/or the configured peer nodes
+// with cluster-autoscaler scale-down-disabled for the duration of the maintenance
+func (m *DrainManagerKubernetes) disableAutoscalerScaleDown() {
+	conf := m.Conf.Kubernetes.Autoscaler.ScaleDownDisabled
+
+	nodeNames := []string{}
+	if conf.Self {
+		nodeNames = append(nodeNames, m.nodeName)
+	}
+
+	if conf.Peers != "" {
+		nodeList := &kubeNodeList{}
+		if err := m.execGetJson(nodeList, "nodes", "--selector", conf.Peers); err != nil {
+			m.Logger.Error("unable to fetch peer nodes", slog.String("selector", conf.Peers), slog.Any("error", err))
+		} else {
+			for _, node := range nodeList.Items {
+				if node.Metadata.Name != m.nodeName {
+					nodeNames = append(nodeNames, node.Metadata.Name)
+				}
+			}
+		}
+	}
+
+	for _, nodeName := range nodeNames {
+		if err := m.updateScaleDownDisabledOwner(nodeName, true); err != nil {
+			m.Logger.Error("unable to disable cluster autoscaler scale down", slog.String("node", nodeName), slog.Any("error", err))
+		}
+	}
+}
+
+// enableAutoscalerScaleDown removes all scale-down-disabled annotations set by this manager,
+// all nodes are checked so annotations are also removed after restarts or changed peer selectors
+func (m *DrainManagerKubernetes) enableAutoscalerScaleDown() bool {
+	nodeList := &kubeNodeList{}
+	if err := m.execGetJson(nodeList, "nodes"); err != nil {
+		m.Logger.Error("unable to fetch nodes", slog.Any("error", err))
+		return false
+	}
+
+	ret := true
+	for _, node := range nodeList.Items {
+		if !stringListContains(scaleDownDisabledOwners(&node), m.nodeName) {
+			continue
+		}
+
+		if err := m.updateScaleDownDisabledOwner(node.Metadata.Name, false); err != nil {
+			m.Logger.Error("unable to enable cluster autoscaler scale down", slog.String("node", node.Metadata.Name), slog.Any("error", err))
+			ret = false
+		}
+	}
+
+	return ret
+}
+
+// updateScaleDownDisabledOwner adds or removes this manager as owner of the scale-down-disabled annotation,
+// the annotation itself is only set by the first owner and removed by the last owner.
+// Nodes with scale-down-disabled annotation which are not managed by any manager are not touched.
+func (m *DrainManagerKubernetes) updateScaleDownDisabledOwner(nodeName string, add bool) error {
+	var lastErr error
+
+	for try := 0; try < kubernetesAnnotationRetryCount; try++ {
+		node := &kubeNode{}
+		if err := m.execGetJson(node, "node", nodeName); err != nil {
+			return err
+		}
+
+		owners := scaleDownDisabledOwners(node)
+		_, annotationExists := node.Metadata.Annotations[KubernetesAnnotationScaleDownDisabled]
+
+		annotateArgs := []string{"annotate", "node", nodeName, "--overwrite=true", fmt.Sprintf("--resource-version=%v", node.Metadata.ResourceVersion)}
+		if add {
+			if stringListContains(owners, m.nodeName) {
+				return nil
+			}
+
+			if annotationExists && len(owners) == 0 {
+				m.Logger.Debug("cluster autoscaler scale down already disabled by someone else", slog.String("node", nodeName))
+				return nil
+			}
+
+			owners = append(owners, m.nodeName)
+			annotateArgs = append(
+				annotateArgs,
+				fmt.Sprintf("%v=true", KubernetesAnnotationScaleDownDisabled),
+				fmt.Sprintf("%v=%v", KubernetesAnnotationScaleDownDisabledOwnedBy, strings.Join(owners, ",")),
+			)
+			m.Logger.Info("disable cluster autoscaler scale down", slog.String("node", nodeName), slog.Any("owners", owners))
+		} else {
+			owners = stringListRemove(owners, m.nodeName)
+			if len(owners) == 0 {
+				annotateArgs = append(
+					annotateArgs,
+					KubernetesAnnotationScaleDownDisabled+"-",
+					KubernetesAnnotationScaleDownDisabledOwnedBy+"-",
+				)
+			} else {
+				annotateArgs = append(
+					annotateArgs,
+					fmt.Sprintf("%v=%v", KubernetesAnnotationScaleDownDisabledOwnedBy, strings.Join(owners, ",")),
+				)
+			}
+			m.Logger.Info("enable cluster autoscaler scale down", slog.String("node", nodeName), slog.Any("remainingOwners", owners))
+		}
+
+		if m.exec(annotateArgs...) {
+			return nil
+		}
+
+		// most likely a conflict because node was modified in the meantime, retry with current version
+		lastErr = fmt.Errorf(`kubectl annotate of node "%v" failed`, nodeName)
+	}
+
+	return lastErr
+}
+
+func scaleDownDisabledOwners(node *kubeNode) []string {
+	ret := []string{}
+	for _, owner := range strings.Split(node.Metadata.Annotations[KubernetesAnnotationScaleDownDisabledOwnedBy], ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			ret = append(ret, owner)
+		}
+	}
+	return ret
+}
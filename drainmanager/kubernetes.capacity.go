@@ -0,0 +1,280 @@
+package drainmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesLabelPlaceholder = "webdevops.io/azure-scheduledevents-manager.placeholder"
+
+	KubernetesPlaceholderPollInterval = 10 * time.Second
+)
+
+type (
+	kubeCapacity struct {
+		Cpu    float64
+		Memory float64
+	}
+
+	kubeCapacityResult struct {
+		Required  kubeCapacity
+		Available kubeCapacity
+		Pods      []kubePod
+	}
+)
+
+func (r *kubeCapacityResult) Sufficient() bool {
+	return r.Available.Cpu >= r.Required.Cpu && r.Available.Memory >= r.Required.Memory
+}
+
+// ensureCapacity checks the remaining capacity of the cluster and creates placeholder pods
+// (if enabled) to trigger cluster autoscaler before pods are evicted
+func (m *DrainManagerKubernetes) ensureCapacity(ctx context.Context, event *azuremetadata.AzureScheduledEvent) {
+	result, err := m.checkCapacity()
+	if err != nil {
+		m.Logger.Error("capacity check failed", slog.String("node", m.nodeName), slog.Any("error", err))
+		return
+	}
+
+	m.prometheus.capacityRequired.WithLabelValues("cpu").Set(result.Required.Cpu)
+	m.prometheus.capacityRequired.WithLabelValues("memory").Set(result.Required.Memory)
+	m.prometheus.capacityAvailable.WithLabelValues("cpu").Set(result.Available.Cpu)
+	m.prometheus.capacityAvailable.WithLabelValues("memory").Set(result.Available.Memory)
+
+	capacityLogger := m.Logger.With(
+		slog.String("node", m.nodeName),
+		slog.Group(
+			"required",
+			slog.Float64("cpu", result.Required.Cpu),
+			slog.Float64("memory", result.Required.Memory),
+		),
+		slog.Group(
+			"available",
+			slog.Float64("cpu", result.Available.Cpu),
+			slog.Float64("memory", result.Available.Memory),
+		),
+	)
+
+	summary := fmt.Sprintf(
+		"required cpu: %.2f, memory: %.0fMi; available cpu: %.2f, memory: %.0fMi",
+		result.Required.Cpu,
+		result.Required.Memory/(1<<20),
+		result.Available.Cpu,
+		result.Available.Memory/(1<<20),
+	)
+
+	if result.Sufficient() {
+		m.prometheus.capacitySufficient.With(prometheus.Labels{}).Set(1)
+		capacityLogger.Info("capacity check passed")
+		m.sendNotification("capacity check for instance %v passed (%v)", m.nodeName, summary)
+		return
+	}
+
+	m.prometheus.capacitySufficient.With(prometheus.Labels{}).Set(0)
+	capacityLogger.Warn("capacity check failed, remaining nodes have insufficient capacity")
+	m.recordEvent(KubernetesEventTypeWarning, "InsufficientCapacity", fmt.Sprintf("remaining nodes have insufficient capacity for %v (%v)", scheduledEventMessage(event), summary))
+
+	if !m.Conf.Kubernetes.Capacity.Overprovision.Enable {
+		m.sendNotification("capacity check for instance %v failed, pods may stay pending (%v)", m.nodeName, summary)
+		return
+	}
+
+	m.sendNotification("capacity check for instance %v failed, creating %v placeholder pods for scale up (%v)", m.nodeName, len(result.Pods), summary)
+	if err := m.createPlaceholderPods(result.Pods); err != nil {
+		capacityLogger.Error("unable to create placeholder pods", slog.Any("error", err))
+		return
+	}
+
+	m.waitForPlaceholderPods(ctx, event)
+	m.deletePlaceholderPods()
+}
+
+// checkCapacity compares the resource requests of the pods on the node (without DaemonSet and mirror pods)
+// with the free allocatable resources of all other schedulable nodes
+func (m *DrainManagerKubernetes) checkCapacity() (*kubeCapacityResult, error) {
+	result := &kubeCapacityResult{}
+
+	nodeList := &kubeNodeList{}
+	if err := m.execGetJson(nodeList, "nodes"); err != nil {
+		return nil, err
+	}
+
+	podList := &kubePodList{}
+	if err := m.execGetJson(podList, "pods", "--all-namespaces"); err != nil {
+		return nil, err
+	}
+
+	requested := map[string]kubeCapacity{}
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" || pod.isTerminated() {
+			continue
+		}
+
+		cpu, memory := pod.resourceRequests()
+		nodeRequests := requested[pod.Spec.NodeName]
+		nodeRequests.Cpu += cpu
+		nodeRequests.Memory += memory
+		requested[pod.Spec.NodeName] = nodeRequests
+
+		if pod.Spec.NodeName == m.nodeName && !pod.isDaemonSetPod() && !pod.isMirrorPod() {
+			result.Required.Cpu += cpu
+			result.Required.Memory += memory
+			result.Pods = append(result.Pods, pod)
+		}
+	}
+
+	for _, node := range nodeList.Items {
+		if node.Metadata.Name == m.nodeName || !node.isSchedulable() {
+			continue
+		}
+
+		if free := parseQuantity(node.Status.Allocatable["cpu"]) - requested[node.Metadata.Name].Cpu; free > 0 {
+			result.Available.Cpu += free
+		}
+
+		if free := parseQuantity(node.Status.Allocatable["memory"]) - requested[node.Metadata.Name].Memory; free > 0 {
+			result.Available.Memory += free
+		}
+	}
+
+	return result, nil
+}
+
+// createPlaceholderPods creates one placeholder pod (with the same resource requests) for each pod of the node
+func (m *DrainManagerKubernetes) createPlaceholderPods(pods []kubePod) error {
+	conf := m.Conf.Kubernetes.Capacity.Overprovision
+
+	items := []interface{}{}
+	for _, pod := range pods {
+		cpu, memory := pod.resourceRequests()
+		if cpu == 0 && memory == 0 {
+			continue
+		}
+
+		spec := map[string]interface{}{
+			"terminationGracePeriodSeconds": 0,
+			"affinity": map[string]interface{}{
+				"nodeAffinity": map[string]interface{}{
+					"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+						"nodeSelectorTerms": []interface{}{
+							map[string]interface{}{
+								"matchExpressions": []interface{}{
+									map[string]interface{}{
+										"key":      "kubernetes.io/hostname",
+										"operator": "NotIn",
+										"values":   []string{m.nodeName},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":  "placeholder",
+					"image": conf.Image,
+					"resources": map[string]interface{}{
+						"requests": map[string]string{
+							"cpu":    fmt.Sprintf("%.0fm", cpu*1000),
+							"memory": fmt.Sprintf("%.0f", memory),
+						},
+					},
+				},
+			},
+		}
+		if conf.PriorityClass != "" {
+			spec["priorityClassName"] = conf.PriorityClass
+		}
+
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"generateName": "azure-scheduledevents-placeholder-",
+				"namespace":    conf.Namespace,
+				"labels": map[string]string{
+					KubernetesLabelPlaceholder: m.nodeName,
+				},
+			},
+			"spec": spec,
+		})
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Logger.Info("create placeholder pods", slog.String("node", m.nodeName), slog.Int("count", len(items)))
+	if !m.execWithInput(payload, "create", "--filename=-") {
+		return fmt.Errorf(`kubectl create of placeholder pods failed`)
+	}
+
+	return nil
+}
+
+// waitForPlaceholderPods waits until all placeholder pods are scheduled to a node,
+// at most the timeout and until NotBefore of the ScheduledEvent minus margin (time left for the eviction)
+func (m *DrainManagerKubernetes) waitForPlaceholderPods(ctx context.Context, event *azuremetadata.AzureScheduledEvent) {
+	conf := m.Conf.Kubernetes.Capacity.Overprovision
+	if conf.Timeout <= 0 || m.Conf.Kubernetes.Drain.DryRun {
+		return
+	}
+
+	deadline := eventDeadline(event, conf.Margin, conf.Timeout)
+	if timeout := time.Now().Add(conf.Timeout); timeout.Before(deadline) {
+		deadline = timeout
+	}
+	waitLogger := m.Logger.With(slog.String("node", m.nodeName), slog.Time("deadline", deadline))
+	waitLogger.Info("waiting for placeholder pods to be scheduled")
+
+	for time.Now().Before(deadline) {
+		podList := &kubePodList{}
+		if err := m.execGetJson(podList, "pods", "--namespace", conf.Namespace, "--selector", fmt.Sprintf("%v=%v", KubernetesLabelPlaceholder, m.nodeName)); err != nil {
+			waitLogger.Warn("unable to fetch placeholder pods", slog.Any("error", err))
+		} else {
+			pending := 0
+			for _, pod := range podList.Items {
+				if pod.Spec.NodeName == "" {
+					pending++
+				}
+			}
+
+			if pending == 0 {
+				waitLogger.Info("all placeholder pods scheduled")
+				return
+			}
+			waitLogger.Info("placeholder pods pending", slog.Int("pending", pending))
+		}
+
+		if !sleepContext(ctx, KubernetesPlaceholderPollInterval) {
+			return
+		}
+	}
+
+	waitLogger.Warn("timeout while waiting for placeholder pods")
+}
+
+func (m *DrainManagerKubernetes) deletePlaceholderPods() {
+	m.Logger.Info("delete placeholder pods", slog.String("node", m.nodeName))
+	if !m.exec("delete", "pods", "--namespace", m.Conf.Kubernetes.Capacity.Overprovision.Namespace, "--selector", fmt.Sprintf("%v=%v", KubernetesLabelPlaceholder, m.nodeName), "--ignore-not-found=true", "--wait=false") {
+		m.Logger.Warn("unable to delete placeholder pods", slog.String("node", m.nodeName))
+	}
+}
@@ -0,0 +1,236 @@
+package drainmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	ScheduledEventResource   = "scheduledevents.azure-scheduledevents.webdevops.io"
+	ScheduledEventApiVersion = "azure-scheduledevents.webdevops.io/v1alpha1"
+
+	ScheduledEventPhaseDetected    = "Detected"
+	ScheduledEventPhaseDraining    = "Draining"
+	ScheduledEventPhaseDrained     = "Drained"
+	ScheduledEventPhaseDrainFailed = "DrainFailed"
+	ScheduledEventPhaseApproved    = "Approved"
+	ScheduledEventPhaseCompleted   = "Completed"
+
+	ScheduledEventCleanupInterval = 15 * time.Minute
+)
+
+var (
+	scheduledEventNameCleanup = regexp.MustCompile(`[^a-z0-9.-]+`)
+)
+
+type (
+	kubeScheduledEvent struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			NodeName string `json:"nodeName"`
+			EventId  string `json:"eventId"`
+			Approved bool   `json:"approved"`
+		} `json:"spec"`
+		Status struct {
+			Phase          string `json:"phase"`
+			CompletionTime string `json:"completionTime"`
+		} `json:"status"`
+	}
+
+	kubeScheduledEventList struct {
+		Items []kubeScheduledEvent `json:"items"`
+	}
+)
+
+func (m *DrainManagerKubernetes) scheduledEventName(event *azuremetadata.AzureScheduledEvent) string {
+	name := strings.ToLower(fmt.Sprintf("%v-%v", m.nodeName, event.EventId))
+	return strings.Trim(scheduledEventNameCleanup.ReplaceAllString(name, "-"), "-.")
+}
+
+// applyScheduledEvent creates or updates the ScheduledEvent resource of the node,
+// spec.approved is not part of the applied manifest and is kept
+func (m *DrainManagerKubernetes) applyScheduledEvent(event *azuremetadata.AzureScheduledEvent) {
+	resources := event.Resources
+	if resources == nil {
+		resources = []string{}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"apiVersion": ScheduledEventApiVersion,
+		"kind":       "ScheduledEvent",
+		"metadata": map[string]interface{}{
+			"name": m.scheduledEventName(event),
+			"labels": map[string]string{
+				KubernetesLabelName: m.nodeName,
+			},
+		},
+		"spec": map[string]interface{}{
+			"nodeName":     m.nodeName,
+			"eventId":      event.EventId,
+			"eventType":    event.EventType,
+			"resourceType": event.ResourceType,
+			"resources":    resources,
+			"eventStatus":  event.EventStatus,
+			"notBefore":    event.NotBefore,
+			"description":  event.Description,
+			"eventSource":  event.EventSource,
+		},
+	})
+	if err != nil {
+		m.Logger.Error("unable to build ScheduledEvent resource", slog.Any("error", err))
+		return
+	}
+
+	m.Logger.Info("apply ScheduledEvent resource", slog.String("node", m.nodeName), slog.String("name", m.scheduledEventName(event)))
+	if !m.execWithInput(payload, "apply", "--filename=-") {
+		m.Logger.Warn("unable to apply ScheduledEvent resource", slog.String("node", m.nodeName))
+		return
+	}
+
+	if m.Conf.Kubernetes.Drain.DryRun {
+		return
+	}
+
+	if scheduledEvent := m.getScheduledEvent(event); scheduledEvent != nil && scheduledEvent.Status.Phase == "" {
+		m.setScheduledEventStatus(event, map[string]interface{}{
+			"phase":   ScheduledEventPhaseDetected,
+			"message": fmt.Sprintf("detected %v", scheduledEventMessage(event)),
+		})
+	}
+}
+
+func (m *DrainManagerKubernetes) getScheduledEvent(event *azuremetadata.AzureScheduledEvent) *kubeScheduledEvent {
+	scheduledEvent := &kubeScheduledEvent{}
+	if err := m.execGetJson(scheduledEvent, ScheduledEventResource, m.scheduledEventName(event)); err != nil {
+		m.Logger.Warn("unable to fetch ScheduledEvent resource", slog.String("node", m.nodeName), slog.Any("error", err))
+		return nil
+	}
+	return scheduledEvent
+}
+
+// setScheduledEventStatus updates the status of the ScheduledEvent resource of the node
+func (m *DrainManagerKubernetes) setScheduledEventStatus(event *azuremetadata.AzureScheduledEvent, status map[string]interface{}) {
+	if !m.Conf.Kubernetes.ScheduledEvents.Enable {
+		return
+	}
+
+	m.patchScheduledEventStatus(m.scheduledEventName(event), status)
+}
+
+func (m *DrainManagerKubernetes) patchScheduledEventStatus(name string, status map[string]interface{}) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"status": status,
+	})
+	if err != nil {
+		m.Logger.Error("unable to build ScheduledEvent status patch", slog.Any("error", err))
+		return
+	}
+
+	m.Logger.Debug("update ScheduledEvent resource status", slog.String("name", name), slog.Any("phase", status["phase"]))
+	if !m.exec("patch", ScheduledEventResource, name, "--subresource=status", "--type=merge", fmt.Sprintf("--patch=%s", payload)) {
+		m.Logger.Warn("unable to update ScheduledEvent resource status", slog.String("name", name))
+	}
+}
+
+// completeScheduledEvents marks all ScheduledEvent resources of the node as completed
+func (m *DrainManagerKubernetes) completeScheduledEvents() {
+	list := &kubeScheduledEventList{}
+	if err := m.execGetJson(list, ScheduledEventResource, "--selector", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName)); err != nil {
+		m.Logger.Warn("unable to fetch ScheduledEvent resources", slog.String("node", m.nodeName), slog.Any("error", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, scheduledEvent := range list.Items {
+		if scheduledEvent.Status.Phase != ScheduledEventPhaseCompleted {
+			m.patchScheduledEventStatus(scheduledEvent.Metadata.Name, map[string]interface{}{
+				"phase":          ScheduledEventPhaseCompleted,
+				"message":        "Azure ScheduledEvent is gone",
+				"completionTime": now.Format(time.RFC3339),
+			})
+		}
+	}
+}
+
+// startScheduledEventsCleanup periodically deletes completed ScheduledEvent resources (of all nodes) older than the retention
+func (m *DrainManagerKubernetes) startScheduledEventsCleanup() {
+	go func() {
+		for {
+			m.cleanupScheduledEvents()
+			time.Sleep(ScheduledEventCleanupInterval)
+		}
+	}()
+}
+
+// cleanupScheduledEvents marks ScheduledEvent resources of nodes which are gone (eg. terminated or scaled in) as completed
+// and deletes completed resources older than the retention
+func (m *DrainManagerKubernetes) cleanupScheduledEvents() {
+	list := &kubeScheduledEventList{}
+	if err := m.execGetJson(list, ScheduledEventResource, "--selector", KubernetesLabelName); err != nil {
+		m.Logger.Warn("unable to fetch ScheduledEvent resources", slog.Any("error", err))
+		return
+	}
+
+	nodeList := &kubeNodeList{}
+	if err := m.execGetJson(nodeList, "nodes"); err != nil {
+		m.Logger.Warn("unable to fetch nodes", slog.Any("error", err))
+		return
+	}
+
+	nodes := map[string]bool{}
+	for _, node := range nodeList.Items {
+		nodes[node.Metadata.Name] = true
+	}
+
+	now := time.Now().UTC()
+	for _, scheduledEvent := range list.Items {
+		if scheduledEvent.Status.Phase != ScheduledEventPhaseCompleted {
+			if !nodes[scheduledEvent.Spec.NodeName] {
+				m.patchScheduledEventStatus(scheduledEvent.Metadata.Name, map[string]interface{}{
+					"phase":          ScheduledEventPhaseCompleted,
+					"message":        "node is gone",
+					"completionTime": now.Format(time.RFC3339),
+				})
+			}
+			continue
+		}
+
+		completionTime, err := time.Parse(time.RFC3339, scheduledEvent.Status.CompletionTime)
+		if err == nil && now.Sub(completionTime) > m.Conf.Kubernetes.ScheduledEvents.Retention {
+			m.Logger.Info("delete ScheduledEvent resource", slog.String("name", scheduledEvent.Metadata.Name))
+			m.exec("delete", ScheduledEventResource, scheduledEvent.Metadata.Name, "--ignore-not-found=true")
+		}
+	}
+}
+
+// ApprovalGranted checks spec.approved of the ScheduledEvent resource if manual approval is enabled
+func (m *DrainManagerKubernetes) ApprovalGranted(event *azuremetadata.AzureScheduledEvent) bool {
+	conf := m.Conf.Kubernetes.ScheduledEvents
+	if !conf.Enable || !conf.ManualApproval {
+		return true
+	}
+
+	if m.Conf.Kubernetes.Drain.DryRun {
+		m.Logger.Info("skipping manual approval (dry-run)", slog.String("node", m.nodeName))
+		return true
+	}
+
+	if scheduledEvent := m.getScheduledEvent(event); scheduledEvent != nil {
+		if scheduledEvent.Spec.Approved {
+			return true
+		}
+	}
+
+	m.Logger.Info(
+		"waiting for manual approval of ScheduledEvent resource",
+		slog.String("node", m.nodeName),
+		slog.String("hint", fmt.Sprintf(`kubectl patch %v %v --type=merge --patch='{"spec":{"approved":true}}'`, ScheduledEventResource, m.scheduledEventName(event))),
+	)
+	return false
+}
@@ -0,0 +1,173 @@
+package drainmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesEventComponent = "azure-scheduledevents-manager"
+
+	KubernetesEventTypeNormal  = "Normal"
+	KubernetesEventTypeWarning = "Warning"
+
+	KubernetesConditionReasonCleared = "NoScheduledMaintenance"
+)
+
+func (m *DrainManagerKubernetes) ScheduledEventDetected(event *azuremetadata.AzureScheduledEvent) {
+	m.recordEvent(KubernetesEventTypeWarning, "ScheduledEventDetected", fmt.Sprintf("detected %v", scheduledEventMessage(event)))
+	m.setNodeCondition("True", event.EventType, scheduledEventMessage(event))
+
+	if m.Conf.Kubernetes.ScheduledEvents.Enable {
+		m.applyScheduledEvent(event)
+	}
+
+	if m.Conf.Kubernetes.Pods.Annotate {
+		m.annotatePods(event)
+	}
+}
+
+func (m *DrainManagerKubernetes) ScheduledEventApproved(event *azuremetadata.AzureScheduledEvent) {
+	m.recordEvent(KubernetesEventTypeNormal, "ScheduledEventApproved", fmt.Sprintf("approved %v", scheduledEventMessage(event)))
+	m.setScheduledEventStatus(event, map[string]interface{}{
+		"phase":        ScheduledEventPhaseApproved,
+		"message":      fmt.Sprintf("approved %v", scheduledEventMessage(event)),
+		"approvalTime": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (m *DrainManagerKubernetes) ScheduledEventCleared() {
+	m.setNodeCondition("False", KubernetesConditionReasonCleared, "no Azure ScheduledEvent for this node")
+
+	if m.Conf.Kubernetes.ScheduledEvents.Enable {
+		m.completeScheduledEvents()
+	}
+
+	if m.Conf.Kubernetes.Pods.Annotate {
+		m.unannotatePods()
+	}
+
+	if m.Conf.Kubernetes.Pods.ReadinessGate != "" {
+		m.readinessGateMaintenance.Store(false)
+		m.setPodReadinessGates("True", KubernetesReadinessGateReasonCleared, "no Azure ScheduledEvent for this node")
+	}
+}
+
+// recordEvent creates a core/v1 Event for the node, visible via "kubectl describe node"
+func (m *DrainManagerKubernetes) recordEvent(eventType, reason, message string) {
+	if !m.Conf.Kubernetes.Events.Enable {
+		return
+	}
+
+	nodeUid := m.nodeName
+	if uid := m.getNodeUid(); uid != "" {
+		nodeUid = uid
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	kubeEvent := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]interface{}{
+			"generateName": fmt.Sprintf("%v.", m.nodeName),
+			"namespace":    m.Conf.Kubernetes.Events.Namespace,
+		},
+		"involvedObject": map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Node",
+			"name":       m.nodeName,
+			"uid":        nodeUid,
+		},
+		"reason":  reason,
+		"message": message,
+		"type":    eventType,
+		"source": map[string]interface{}{
+			"component": KubernetesEventComponent,
+			"host":      m.nodeName,
+		},
+		"reportingComponent": KubernetesEventComponent,
+		"reportingInstance":  m.nodeName,
+		"firstTimestamp":     now,
+		"lastTimestamp":      now,
+		"count":              1,
+	}
+
+	payload, err := json.Marshal(kubeEvent)
+	if err != nil {
+		m.Logger.Error("unable to build kubernetes event", slog.Any("error", err))
+		return
+	}
+
+	m.Logger.Debug("record kubernetes event", slog.String("node", m.nodeName), slog.String("reason", reason))
+	if !m.execWithInput(payload, "create", "--filename=-") {
+		m.Logger.Warn("unable to record kubernetes event", slog.String("node", m.nodeName), slog.String("reason", reason))
+	}
+}
+
+// getNodeUid returns the uid of the node, cached by VerifyNode or fetched once
+func (m *DrainManagerKubernetes) getNodeUid() string {
+	m.nodeUidLock.Lock()
+	defer m.nodeUidLock.Unlock()
+
+	if m.nodeUid == "" {
+		if node, err := m.getNode(); err == nil {
+			m.nodeUid = node.Metadata.UID
+		}
+	}
+
+	return m.nodeUid
+}
+
+func (m *DrainManagerKubernetes) setNodeUid(uid string) {
+	m.nodeUidLock.Lock()
+	defer m.nodeUidLock.Unlock()
+
+	m.nodeUid = uid
+}
+
+// setNodeCondition sets (or replaces) the configured node condition via status subresource
+func (m *DrainManagerKubernetes) setNodeCondition(status, reason, message string) {
+	if !m.Conf.Kubernetes.NodeCondition.Enable {
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []map[string]interface{}{
+				{
+					"type":               m.Conf.Kubernetes.NodeCondition.Type,
+					"status":             status,
+					"reason":             reason,
+					"message":            message,
+					"lastHeartbeatTime":  now,
+					"lastTransitionTime": now,
+				},
+			},
+		},
+	}
+
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		m.Logger.Error("unable to build node condition patch", slog.Any("error", err))
+		return
+	}
+
+	m.Logger.Info("set node condition", slog.String("node", m.nodeName), slog.String("condition", m.Conf.Kubernetes.NodeCondition.Type), slog.String("status", status), slog.String("reason", reason))
+	if !m.exec("patch", "node", m.nodeName, "--subresource=status", "--type=strategic", fmt.Sprintf("--patch=%s", payload)) {
+		m.Logger.Warn("unable to set node condition", slog.String("node", m.nodeName))
+	}
+}
+
+func scheduledEventMessage(event *azuremetadata.AzureScheduledEvent) string {
+	notBefore := event.NotBefore
+	if notBefore == "" {
+		notBefore = "now"
+	}
+
+	return fmt.Sprintf("Azure ScheduledEvent %v (EventType: %v, NotBefore: %v, EventStatus: %v, EventSource: %v)", event.EventId, event.EventType, notBefore, event.EventStatus, event.EventSource)
+}
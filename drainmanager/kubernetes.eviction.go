@@ -0,0 +1,372 @@
+package drainmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webdevops/go-common/log/slogger"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+)
+
+const (
+	KubernetesEvictionPollInterval = 5 * time.Second
+
+	// default of terminationGracePeriodSeconds if not set in pod spec
+	KubernetesDefaultTerminationGracePeriod = 30 * time.Second
+
+	KubernetesMinGracePeriod = 1 * time.Second
+)
+
+// runDrainStages evicts the pods of the node stage by stage (ordered), DaemonSet and mirror pods are always skipped
+func (m *DrainManagerKubernetes) runDrainStages(ctx context.Context, event *azuremetadata.AzureScheduledEvent, stages config.KubeDrainStageList) bool {
+	ret := true
+	for _, stage := range stages {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if !m.runDrainStage(ctx, event, stage) {
+			ret = false
+		}
+	}
+	return ret
+}
+
+func (m *DrainManagerKubernetes) runDrainStage(ctx context.Context, event *azuremetadata.AzureScheduledEvent, stage config.KubeDrainStage) bool {
+	stageLogger := m.Logger.With(slog.String("node", m.nodeName), slog.Group("stage", slog.String("name", stage.Name), slog.String("selector", stage.Selector)))
+
+	selectors := []string{}
+	if stage.Selector != "" {
+		selectors = append(selectors, stage.Selector)
+	}
+
+	if stage.DrainArgsFilter {
+		// pods not matching --pod-selector are not evicted by kubectl drain either
+		if podSelector := m.drainArgValue("--pod-selector"); podSelector != "" {
+			selectors = append(selectors, podSelector)
+		}
+	}
+
+	args := []string{}
+	if len(selectors) > 0 {
+		args = append(args, "--selector", strings.Join(selectors, ","))
+	}
+
+	podList, err := m.nodePods(args...)
+	if err != nil {
+		stageLogger.Error("unable to fetch pods for eviction stage", slog.Any("error", err))
+		return false
+	}
+
+	pods := []kubePod{}
+	for _, pod := range podList {
+		if pod.isDaemonSetPod() || pod.isMirrorPod() || pod.isTerminated() {
+			continue
+		}
+
+		if stage.DrainArgsFilter {
+			// same safety checks as kubectl drain, skipped pods are left to kubectl drain
+			if pod.controllerRef() == nil && !m.drainArgEnabled("--force") {
+				stageLogger.Info("skipping pod without controller (no --force in drain args)", slog.String("namespace", pod.Metadata.Namespace), slog.String("pod", pod.Metadata.Name))
+				continue
+			}
+
+			if pod.hasLocalStorage() && !m.drainArgEnabled("--delete-emptydir-data") && !m.drainArgEnabled("--delete-local-data") {
+				stageLogger.Info("skipping pod with local storage (no --delete-emptydir-data in drain args)", slog.String("namespace", pod.Metadata.Namespace), slog.String("pod", pod.Metadata.Name))
+				continue
+			}
+		}
+
+		pods = append(pods, pod)
+	}
+
+	deadline := eventDeadline(event, 0, stage.Timeout)
+	if stageDeadline := time.Now().Add(stage.Timeout); stageDeadline.Before(deadline) {
+		deadline = stageDeadline
+	}
+
+	stageLogger.Info("starting eviction stage", slog.Int("pods", len(pods)), slog.Time("deadline", deadline))
+
+	parallel := stage.Parallel
+	if parallel <= 0 || parallel > len(pods) {
+		parallel = len(pods)
+	}
+
+	ret := true
+	for start := 0; start < len(pods); start += parallel {
+		end := start + parallel
+		if end > len(pods) {
+			end = len(pods)
+		}
+
+		if ctx.Err() != nil {
+			stageLogger.Warn("eviction stage cancelled")
+			return false
+		}
+
+		if !m.evictPodBatch(ctx, stageLogger, event, stage, pods[start:end], deadline) {
+			ret = false
+		}
+	}
+
+	stageLogger.Info("finished eviction stage", slog.Bool("success", ret))
+	return ret
+}
+
+// drainArgValue returns the value of the option in --kube.drain.args (--name=value or --name value)
+func (m *DrainManagerKubernetes) drainArgValue(name string) string {
+	ret := ""
+	args := m.Conf.Kubernetes.Drain.Args
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			ret = args[i+1]
+		} else if value, found := strings.CutPrefix(arg, name+"="); found {
+			ret = value
+		}
+	}
+	return ret
+}
+
+// drainArgEnabled checks if the boolean flag is enabled in the kubectl drain args (eg. "--force" or "--force=true")
+func (m *DrainManagerKubernetes) drainArgEnabled(name string) bool {
+	enabled := false
+	for _, arg := range m.Conf.Kubernetes.Drain.Args {
+		if arg == name {
+			enabled = true
+		} else if value, found := strings.CutPrefix(arg, name+"="); found {
+			enabled, _ = strconv.ParseBool(value)
+		}
+	}
+	return enabled
+}
+
+// evictPodBatch evicts the pods in parallel and waits until they are gone (and optionally rescheduled and ready)
+func (m *DrainManagerKubernetes) evictPodBatch(ctx context.Context, stageLogger *slogger.Logger, event *azuremetadata.AzureScheduledEvent, stage config.KubeDrainStage, pods []kubePod, deadline time.Time) bool {
+	// ready replicas of controllers on other nodes before eviction
+	readyBefore := map[string]int{}
+	evictedCount := map[string]int{}
+	if stage.WaitReady {
+		for _, pod := range pods {
+			if ownerRef := pod.controllerRef(); ownerRef != nil {
+				if _, exists := readyBefore[ownerRef.UID]; !exists {
+					readyBefore[ownerRef.UID] = m.countReadyReplacements(&pod)
+				}
+				evictedCount[ownerRef.UID]++
+			}
+		}
+	}
+
+	lock := sync.Mutex{}
+	ret := true
+	wg := sync.WaitGroup{}
+	for _, row := range pods {
+		pod := row
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			podLogger := stageLogger.With(slog.String("namespace", pod.Metadata.Namespace), slog.String("pod", pod.Metadata.Name))
+
+			if m.Conf.Kubernetes.Drain.Hooks.Enable && !stage.SkipHooks {
+				if _, exists := pod.Metadata.Annotations[KubernetesAnnotationHookPort]; exists {
+					m.runPodHook(ctx, &pod, event)
+				}
+			}
+
+			gracePeriod := stage.GracePeriod
+			if m.Conf.Kubernetes.Drain.GracePeriod.Dynamic {
+				gracePeriod = m.dynamicGracePeriod(podLogger, &pod, event, stage.GracePeriod)
+			}
+
+			if !m.evictPod(ctx, podLogger, &pod, gracePeriod, deadline) || !m.waitForPodDeletion(ctx, podLogger, &pod, deadline) {
+				lock.Lock()
+				ret = false
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stage.WaitReady && !m.Conf.Kubernetes.Drain.DryRun {
+		for _, pod := range pods {
+			ownerRef := pod.controllerRef()
+			if ownerRef == nil {
+				continue
+			}
+
+			if _, exists := evictedCount[ownerRef.UID]; !exists {
+				continue
+			}
+
+			expected := readyBefore[ownerRef.UID] + evictedCount[ownerRef.UID]
+			if !m.waitForReadyReplacements(ctx, stageLogger, &pod, expected, deadline) {
+				ret = false
+			}
+			delete(evictedCount, ownerRef.UID)
+		}
+	}
+
+	return ret
+}
+
+// dynamicGracePeriod calculates the grace period of the pod from the remaining time until NotBefore (minus margin),
+// capped by the terminationGracePeriodSeconds of the pod (and the grace period of the stage if set)
+func (m *DrainManagerKubernetes) dynamicGracePeriod(podLogger *slogger.Logger, pod *kubePod, event *azuremetadata.AzureScheduledEvent, stageGracePeriod time.Duration) time.Duration {
+	podGracePeriod := KubernetesDefaultTerminationGracePeriod
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		podGracePeriod = time.Duration(*pod.Spec.TerminationGracePeriodSeconds) * time.Second
+	}
+
+	remaining := time.Until(eventDeadline(event, m.Conf.Kubernetes.Drain.GracePeriod.Margin, 0))
+
+	gracePeriod := remaining.Truncate(time.Second)
+	if gracePeriod > podGracePeriod {
+		gracePeriod = podGracePeriod
+	}
+
+	if stageGracePeriod > 0 && gracePeriod > stageGracePeriod {
+		gracePeriod = stageGracePeriod
+	}
+
+	if gracePeriod < KubernetesMinGracePeriod {
+		gracePeriod = KubernetesMinGracePeriod
+	}
+
+	podLogger.Info(
+		"calculated eviction grace period",
+		slog.Duration("gracePeriod", gracePeriod),
+		slog.Duration("remaining", remaining.Truncate(time.Second)),
+		slog.Duration("margin", m.Conf.Kubernetes.Drain.GracePeriod.Margin),
+		slog.Duration("terminationGracePeriod", podGracePeriod),
+	)
+
+	return gracePeriod
+}
+
+// evictPod creates an Eviction (respects PodDisruptionBudgets) and retries until the deadline
+func (m *DrainManagerKubernetes) evictPod(ctx context.Context, podLogger *slogger.Logger, pod *kubePod, gracePeriod time.Duration, deadline time.Time) bool {
+	eviction := map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata": map[string]interface{}{
+			"name":      pod.Metadata.Name,
+			"namespace": pod.Metadata.Namespace,
+		},
+	}
+
+	if gracePeriod > 0 {
+		eviction["deleteOptions"] = map[string]interface{}{
+			"gracePeriodSeconds": int64(gracePeriod.Seconds()),
+		}
+	}
+
+	payload, err := json.Marshal(eviction)
+	if err != nil {
+		podLogger.Error("unable to build eviction", slog.Any("error", err))
+		return false
+	}
+
+	if m.Conf.Kubernetes.Drain.DryRun {
+		podLogger.Info("evict pod (dry-run)", slog.Duration("gracePeriod", gracePeriod))
+		return true
+	}
+
+	evictionUrl := fmt.Sprintf("/api/v1/namespaces/%v/pods/%v/eviction", pod.Metadata.Namespace, pod.Metadata.Name)
+	for {
+		podLogger.Info("evict pod", slog.Duration("gracePeriod", gracePeriod))
+		if m.execWithInput(payload, "create", "--raw", evictionUrl, "--filename=-") {
+			return true
+		}
+
+		// eviction might be blocked by PodDisruptionBudget, retry until deadline
+		if time.Now().Add(KubernetesEvictionPollInterval).After(deadline) {
+			podLogger.Warn("unable to evict pod before deadline")
+			return false
+		}
+		if !sleepContext(ctx, KubernetesEvictionPollInterval) {
+			return false
+		}
+	}
+}
+
+// waitForPodDeletion waits until the pod (identified by uid) is gone
+func (m *DrainManagerKubernetes) waitForPodDeletion(ctx context.Context, podLogger *slogger.Logger, pod *kubePod, deadline time.Time) bool {
+	if m.Conf.Kubernetes.Drain.DryRun {
+		return true
+	}
+
+	for {
+		output, err := m.runComandOutput(m.kubectlCommand("get", "pod", pod.Metadata.Name, "--namespace", pod.Metadata.Namespace, "--ignore-not-found=true", "--output=json"))
+		if err == nil {
+			current := &kubePod{}
+			if len(output) == 0 {
+				return true
+			} else if err := json.Unmarshal(output, current); err == nil && current.Metadata.UID != pod.Metadata.UID {
+				return true
+			}
+		}
+
+		if time.Now().Add(KubernetesEvictionPollInterval).After(deadline) {
+			podLogger.Warn("pod still exists at deadline")
+			return false
+		}
+		if !sleepContext(ctx, KubernetesEvictionPollInterval) {
+			return false
+		}
+	}
+}
+
+// countReadyReplacements counts the ready pods of the same controller on other nodes
+func (m *DrainManagerKubernetes) countReadyReplacements(pod *kubePod) int {
+	ownerRef := pod.controllerRef()
+	if ownerRef == nil {
+		return 0
+	}
+
+	podList := &kubePodList{}
+	if err := m.execGetJson(podList, "pods", "--namespace", pod.Metadata.Namespace); err != nil {
+		m.Logger.Warn("unable to fetch pods", slog.String("namespace", pod.Metadata.Namespace), slog.Any("error", err))
+		return 0
+	}
+
+	ret := 0
+	for _, row := range podList.Items {
+		if rowOwnerRef := row.controllerRef(); rowOwnerRef != nil && rowOwnerRef.UID == ownerRef.UID {
+			if row.Spec.NodeName != m.nodeName && row.isReady() {
+				ret++
+			}
+		}
+	}
+	return ret
+}
+
+// waitForReadyReplacements waits until the controller of the pod has the expected number of ready pods on other nodes
+func (m *DrainManagerKubernetes) waitForReadyReplacements(ctx context.Context, stageLogger *slogger.Logger, pod *kubePod, expected int, deadline time.Time) bool {
+	ownerRef := pod.controllerRef()
+	waitLogger := stageLogger.With(slog.String("namespace", pod.Metadata.Namespace), slog.String("owner", fmt.Sprintf("%v/%v", ownerRef.Kind, ownerRef.Name)), slog.Int("expectedReady", expected))
+	waitLogger.Info("waiting for rescheduled pods to be ready")
+
+	for {
+		ready := m.countReadyReplacements(pod)
+		if ready >= expected {
+			waitLogger.Info("rescheduled pods are ready", slog.Int("ready", ready))
+			return true
+		}
+
+		if time.Now().Add(KubernetesEvictionPollInterval).After(deadline) {
+			waitLogger.Warn("rescheduled pods not ready before deadline", slog.Int("ready", ready))
+			return false
+		}
+		if !sleepContext(ctx, KubernetesEvictionPollInterval) {
+			return false
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package drainmanager
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/utkuozdemir/go-slogio"
 	"github.com/webdevops/go-common/log/slogger"
 
@@ -14,15 +22,107 @@ import (
 	"github.com/webdevops/azure-scheduledevents-manager/config"
 )
 
+const (
+	KubernetesLabelName             = "webdevops.io/azure-scheduledevents-manager"
+	KubernetesLabelMaintenanceAt    = "webdevops.io/azure-scheduledevents-manager.maintenance-at"
+	KubernetesTaintName             = "webdevops.io/azure-scheduledevents-manager"
+	KubernetesTaintEffectPrefer     = "PreferNoSchedule"
+	KubernetesTaintEffectNoSchedule = "NoSchedule"
+
+	// NoExecute taint of older versions (preempt fast path), only removed on uncordon
+	KubernetesTaintEffectExecute = "NoExecute"
+)
+
 type DrainManagerKubernetes struct {
 	DrainManager
 	Conf   config.Opts
 	Logger *slogger.Logger
+	Notify func(message string, args ...interface{})
 
 	nodeName string
+
+	// cancels the running drain (eg. by preempt fast path)
+	drainCancel     context.CancelFunc
+	drainCancelLock sync.Mutex
+
+	// uid of node (for involvedObject of events), fetched once
+	nodeUid     string
+	nodeUidLock sync.Mutex
+
+	// last known state of the pause switches, kept if the switches can't be fetched
+	paused       bool
+	pausedReason string
+	pausedLock   sync.Mutex
+
+	// summaries of the drain reports finished by the last uncordon
+	drainReportSummaries []string
+
+	// readiness gate of pods is set to False for maintenance
+	readinessGateMaintenance atomic.Bool
+	readinessGateLock        sync.Mutex
+
+	prometheus struct {
+		capacityRequired   *prometheus.GaugeVec
+		capacityAvailable  *prometheus.GaugeVec
+		capacitySufficient *prometheus.GaugeVec
+		kuredLockWait      *prometheus.GaugeVec
+	}
+}
+
+func (m *DrainManagerKubernetes) Init() {
+	m.initMetrics()
+
+	if m.Conf.Kubernetes.Pods.ReadinessGate != "" {
+		m.startReadinessGateReconciler()
+	}
+
+	if m.Conf.Kubernetes.ScheduledEvents.Enable {
+		m.startScheduledEventsCleanup()
+	}
+}
+
+func (m *DrainManagerKubernetes) initMetrics() {
+	m.prometheus.capacityRequired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_kube_capacity_required",
+			Help: "Azure ScheduledEvent resources requested by pods of the node (cpu in cores, memory in bytes)",
+		},
+		[]string{"resource"},
+	)
+	prometheus.MustRegister(m.prometheus.capacityRequired)
+
+	m.prometheus.capacityAvailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_kube_capacity_available",
+			Help: "Azure ScheduledEvent resources available on remaining schedulable nodes (cpu in cores, memory in bytes)",
+		},
+		[]string{"resource"},
+	)
+	prometheus.MustRegister(m.prometheus.capacityAvailable)
+
+	m.prometheus.capacitySufficient = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_kube_capacity_sufficient",
+			Help: "Azure ScheduledEvent result of capacity check before drain (1 = sufficient)",
+		},
+		[]string{},
+	)
+	prometheus.MustRegister(m.prometheus.capacitySufficient)
+
+	m.prometheus.kuredLockWait = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_kube_kured_lock_wait_seconds",
+			Help: "Azure ScheduledEvent time spent waiting for kured lock before drain",
+		},
+		[]string{},
+	)
+	prometheus.MustRegister(m.prometheus.kuredLockWait)
 }
 
 func (m *DrainManagerKubernetes) SetInstanceName(name string) {
+	if name != m.nodeName {
+		m.setNodeUid("")
+	}
 	m.nodeName = name
 }
 
@@ -37,28 +137,301 @@ func (m *DrainManagerKubernetes) Test() error {
 	return nil
 }
 
+func (m *DrainManagerKubernetes) Taint(event *azuremetadata.AzureScheduledEvent) bool {
+	maintenanceAt := "0"
+	if eventValue, err := event.NotBeforeUnixTimestamp(); err == nil {
+		maintenanceAt = fmt.Sprintf("%.0f", eventValue)
+	}
+
+	m.disableAutoscalerScaleDown()
+
+	// Label
+	m.Logger.Info("label node with maintenance time", slog.String("node", m.nodeName), slog.String("maintenanceAt", maintenanceAt))
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v", KubernetesLabelMaintenanceAt, maintenanceAt)) {
+		return false
+	}
+
+	// TAINT
+	m.Logger.Info("taint node", slog.String("node", m.nodeName), slog.String("effect", KubernetesTaintEffectPrefer))
+	return m.exec("taint", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v:%v", KubernetesTaintName, event.EventType, KubernetesTaintEffectPrefer))
+}
+
+func (m *DrainManagerKubernetes) Cordon(event *azuremetadata.AzureScheduledEvent) bool {
+	m.disableAutoscalerScaleDown()
+
+	// Label
+	m.Logger.Info("label node", slog.String("node", m.nodeName))
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName)) {
+		return false
+	}
+
+	// CORDON
+	m.Logger.Info("cordon node", slog.String("node", m.nodeName))
+	return m.exec("cordon", m.nodeName)
+}
+
 func (m *DrainManagerKubernetes) Drain(event *azuremetadata.AzureScheduledEvent) bool {
+	return m.runDrain(event, m.drain)
+}
+
+// CancelDrain cancels the waits and eviction stages of a running drain (eg. for preempt fast path)
+func (m *DrainManagerKubernetes) CancelDrain() {
+	m.drainCancelLock.Lock()
+	defer m.drainCancelLock.Unlock()
+
+	if m.drainCancel != nil {
+		m.Logger.Warn("cancelling running drain", slog.String("node", m.nodeName))
+		m.drainCancel()
+	}
+}
+
+// runDrain runs the drain and tracks the progress in the ScheduledEvent resource,
+// the drain report (if enabled) is persisted and finished after the maintenance by Uncordon
+func (m *DrainManagerKubernetes) runDrain(event *azuremetadata.AzureScheduledEvent, drain func(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.drainCancelLock.Lock()
+	m.drainCancel = cancel
+	m.drainCancelLock.Unlock()
+
+	defer func() {
+		m.drainCancelLock.Lock()
+		m.drainCancel = nil
+		m.drainCancelLock.Unlock()
+		cancel()
+	}()
+
+	var report *DrainReport
+	if m.Conf.Kubernetes.Drain.Report.Enable {
+		report = m.startDrainReport(event)
+		m.saveDrainReport(report)
+	}
+
+	m.setScheduledEventStatus(event, map[string]interface{}{
+		"phase":          ScheduledEventPhaseDraining,
+		"message":        fmt.Sprintf("draining node for %v", scheduledEventMessage(event)),
+		"drainStartTime": time.Now().UTC().Format(time.RFC3339),
+	})
+
+	ret := drain(ctx, event)
+	if ctx.Err() != nil {
+		m.Logger.Warn("drain cancelled", slog.String("node", m.nodeName))
+		ret = false
+	}
+
+	status := map[string]interface{}{
+		"phase":           ScheduledEventPhaseDrained,
+		"message":         fmt.Sprintf("drain finished for %v", scheduledEventMessage(event)),
+		"drainFinishTime": time.Now().UTC().Format(time.RFC3339),
+	}
+	if !ret {
+		status["phase"] = ScheduledEventPhaseDrainFailed
+		status["message"] = fmt.Sprintf("drain failed for %v", scheduledEventMessage(event))
+	}
+	m.setScheduledEventStatus(event, status)
+
+	if report != nil {
+		report.Success = ret
+		report.DrainFinishTime = time.Now()
+		m.saveDrainReport(report)
+	}
+
+	return ret
+}
+
+func (m *DrainManagerKubernetes) drain(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
 	// Label
 	m.Logger.Info("label node", slog.String("node", m.nodeName))
-	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("webdevops.io/azure-scheduledevents-manager=%v", m.nodeName)) {
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName)) {
+		return false
+	}
+
+	if !m.preDrain(ctx, event) {
 		return false
 	}
 
+	stages := append(config.KubeDrainStageList{}, m.Conf.Kubernetes.Drain.Stages...)
+	if m.Conf.Kubernetes.Drain.GracePeriod.Dynamic {
+		// evict all remaining pods with dynamic grace period, kubectl drain only handles leftovers
+		stages = append(stages, config.KubeDrainStage{
+			Name:            "remaining",
+			Timeout:         config.KubeDrainStageDefaultTimeout,
+			DrainArgsFilter: true,
+		})
+	}
+
+	if len(stages) > 0 {
+		// cordon first, evicted pods must not be scheduled on this node again
+		m.Logger.Info("cordon node", slog.String("node", m.nodeName))
+		if !m.exec("cordon", m.nodeName) {
+			return false
+		}
+
+		m.Logger.Info("run eviction stages", slog.String("node", m.nodeName), slog.Int("stages", len(stages)))
+		if !m.runDrainStages(ctx, event, stages) {
+			m.Logger.Warn("eviction stages finished with errors, continuing with drain", slog.String("node", m.nodeName))
+		}
+	}
+
+	m.runPreEvictionHooks(ctx, event)
+
 	// DRAIN
 	m.Logger.Info("drain node", slog.String("node", m.nodeName))
+	m.recordEvent(KubernetesEventTypeWarning, "DrainStarted", fmt.Sprintf("draining node for %v", scheduledEventMessage(event)))
 	kubectlDrainOpts := []string{"drain", m.nodeName}
 	kubectlDrainOpts = append(kubectlDrainOpts, m.Conf.Kubernetes.Drain.Args...)
-	return m.exec(kubectlDrainOpts...)
+	if !m.execContext(ctx, kubectlDrainOpts...) {
+		m.recordEvent(KubernetesEventTypeWarning, "DrainFailed", fmt.Sprintf("drain failed for %v", scheduledEventMessage(event)))
+		return false
+	}
+
+	if m.Conf.Kubernetes.Drain.VolumeDetach.Enable {
+		m.waitForVolumeDetach(ctx, event)
+	}
+
+	m.recordEvent(KubernetesEventTypeNormal, "DrainFinished", fmt.Sprintf("drain finished for %v", scheduledEventMessage(event)))
+
+	return true
+}
+
+// Preempt is the fast path for Preempt ScheduledEvents: NoSchedule taint, cordon and parallel eviction with short grace period
+// (no NoExecute taint, it would also evict the manager itself and DaemonSet pods without toleration)
+func (m *DrainManagerKubernetes) Preempt(event *azuremetadata.AzureScheduledEvent) bool {
+	// Label
+	m.Logger.Info("label node", slog.String("node", m.nodeName))
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName)) {
+		return false
+	}
+
+	// TAINT
+	m.Logger.Info("taint node", slog.String("node", m.nodeName), slog.String("effect", KubernetesTaintEffectNoSchedule))
+	if !m.exec("taint", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=%v:%v", KubernetesTaintName, event.EventType, KubernetesTaintEffectNoSchedule)) {
+		return false
+	}
+
+	// CORDON
+	m.Logger.Info("cordon node", slog.String("node", m.nodeName))
+	if !m.exec("cordon", m.nodeName) {
+		return false
+	}
+
+	// no time to wait for load balancer updates
+	if m.Conf.Kubernetes.LoadBalancer.Exclude {
+		m.excludeFromLoadBalancers(context.Background(), 0)
+	}
+
+	if m.Conf.Kubernetes.Pods.ReadinessGate != "" {
+		m.readinessGateMaintenance.Store(true)
+		m.setPodReadinessGates("False", KubernetesReadinessGateReasonMaintenance, scheduledEventMessage(event))
+	}
+
+	m.recordEvent(KubernetesEventTypeWarning, "PreemptStarted", fmt.Sprintf("evicting pods for %v", scheduledEventMessage(event)))
+	stage := config.KubeDrainStage{
+		Name:        "preempt",
+		GracePeriod: m.Conf.Drain.Preempt.GracePeriod,
+		Timeout:     time.Until(eventDeadline(event, 0, m.Conf.Drain.Preempt.GracePeriod)),
+		// hooks (default timeout 30s) could use up the whole notice window before eviction
+		SkipHooks: true,
+	}
+	if stage.Timeout < m.Conf.Drain.Preempt.GracePeriod {
+		// NotBefore already reached, try at least once
+		stage.Timeout = m.Conf.Drain.Preempt.GracePeriod
+	}
+	if !m.runDrainStage(context.Background(), event, stage) {
+		m.recordEvent(KubernetesEventTypeWarning, "PreemptFailed", fmt.Sprintf("eviction not finished for %v", scheduledEventMessage(event)))
+		return false
+	}
+	m.recordEvent(KubernetesEventTypeNormal, "PreemptFinished", fmt.Sprintf("eviction finished for %v", scheduledEventMessage(event)))
+
+	return true
 }
 
 func (m *DrainManagerKubernetes) Uncordon() bool {
+	node, err := m.getNode()
+	if err != nil {
+		m.Logger.Error("unable to fetch node", slog.String("node", m.nodeName), slog.Any("error", err))
+		return false
+	}
+
 	m.Logger.Info("uncordon node", slog.String("node", m.nodeName))
-	if !m.exec("uncordon", "-l", fmt.Sprintf("webdevops.io/azure-scheduledevents-manager=%v", m.nodeName)) {
+	if !m.exec("uncordon", "-l", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName)) {
 		return false
 	}
 
+	if _, exists := node.Metadata.Labels[KubernetesLabelName]; exists {
+		m.recordEvent(KubernetesEventTypeNormal, "Uncordoned", "node uncordoned, Azure ScheduledEvent is gone")
+	}
+
 	m.Logger.Info("remove label node", slog.String("node", m.nodeName))
-	return m.exec("label", "node", m.nodeName, "--overwrite=true", "webdevops.io/azure-scheduledevents-manager-")
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", KubernetesLabelName+"-", KubernetesLabelMaintenanceAt+"-") {
+		return false
+	}
+
+	if m.Conf.Kubernetes.Capacity.Overprovision.Enable {
+		m.deletePlaceholderPods()
+	}
+
+	if !m.enableAutoscalerScaleDown() {
+		return false
+	}
+
+	if m.Conf.Kubernetes.Kured.Mode == KuredLockModeAcquire && !m.releaseKuredLock() {
+		return false
+	}
+
+	// also if disabled in the meantime, label must not stay after maintenance
+	if !m.includeInLoadBalancers(node) {
+		return false
+	}
+
+	for _, effect := range []string{KubernetesTaintEffectPrefer, KubernetesTaintEffectNoSchedule, KubernetesTaintEffectExecute} {
+		if node.hasTaint(KubernetesTaintName, effect) {
+			m.Logger.Info("remove taint node", slog.String("node", m.nodeName), slog.String("effect", effect))
+			if !m.exec("taint", "node", m.nodeName, fmt.Sprintf("%v:%v-", KubernetesTaintName, effect)) {
+				return false
+			}
+		}
+	}
+
+	m.drainReportSummaries = nil
+	if m.Conf.Kubernetes.Drain.Report.Enable {
+		m.drainReportSummaries = m.finishDrainReports()
+	}
+
+	return true
+}
+
+// DrainReport returns the summaries of the drain reports finished by the last Uncordon
+func (m *DrainManagerKubernetes) DrainReport() string {
+	return strings.Join(m.drainReportSummaries, "; ")
+}
+
+func (m *DrainManagerKubernetes) sendNotification(message string, args ...interface{}) {
+	if m.Notify != nil {
+		m.Notify(message, args...)
+	}
+}
+
+func (m *DrainManagerKubernetes) getNode() (*kubeNode, error) {
+	node := &kubeNode{}
+	if err := m.execGetJson(node, "node", m.nodeName); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// nodePods returns all pods of the node
+func (m *DrainManagerKubernetes) nodePods(args ...string) ([]kubePod, error) {
+	podList := &kubePodList{}
+	kubectlArgs := []string{"--all-namespaces", fmt.Sprintf("--field-selector=spec.nodeName=%v", m.nodeName)}
+	kubectlArgs = append(kubectlArgs, args...)
+	if err := m.execGetJson(podList, "pods", kubectlArgs...); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+func (m *DrainManagerKubernetes) kubectlCommand(args ...string) *exec.Cmd {
+	return exec.Command("kubectl", args...) // #nosec G204
 }
 
 func (m *DrainManagerKubernetes) execGet(resourceType string, args ...string) bool {
@@ -71,6 +444,31 @@ func (m *DrainManagerKubernetes) execGet(resourceType string, args ...string) bo
 	return m.runComand(exec.Command("kubectl", kubectlArgs...)) // #nosec G204
 }
 
+func (m *DrainManagerKubernetes) execGetJson(target interface{}, resourceType string, args ...string) error {
+	kubectlArgs := []string{
+		"get",
+		"--output=json",
+		resourceType,
+	}
+	kubectlArgs = append(kubectlArgs, args...)
+
+	output, err := m.runComandOutput(exec.Command("kubectl", kubectlArgs...)) // #nosec G204
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(output, target)
+}
+
+// execContext runs kubectl like exec, the process is killed if the context is cancelled
+func (m *DrainManagerKubernetes) execContext(ctx context.Context, args ...string) bool {
+	if m.Conf.Kubernetes.Drain.DryRun {
+		args = append(args, "--dry-run=client")
+	}
+
+	return m.runComand(exec.CommandContext(ctx, "kubectl", args...)) // #nosec G204
+}
+
 func (m *DrainManagerKubernetes) exec(args ...string) bool {
 	if m.Conf.Kubernetes.Drain.DryRun {
 		args = append(args, "--dry-run=client")
@@ -79,6 +477,16 @@ func (m *DrainManagerKubernetes) exec(args ...string) bool {
 	return m.runComand(exec.Command("kubectl", args...))
 }
 
+func (m *DrainManagerKubernetes) execWithInput(input []byte, args ...string) bool {
+	if m.Conf.Kubernetes.Drain.DryRun {
+		args = append(args, "--dry-run=client")
+	}
+
+	cmd := exec.Command("kubectl", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	return m.runComand(cmd)
+}
+
 func (m *DrainManagerKubernetes) runComand(cmd *exec.Cmd) bool {
 	cmd.Env = os.Environ()
 
@@ -97,3 +505,76 @@ func (m *DrainManagerKubernetes) runComand(cmd *exec.Cmd) bool {
 	}
 	return true
 }
+
+func (m *DrainManagerKubernetes) runComandOutput(cmd *exec.Cmd) ([]byte, error) {
+	cmd.Env = os.Environ()
+
+	cmdLogger := m.Logger.With(slog.String("command", "kubectl"))
+	writer := &slogio.Writer{Log: cmdLogger.Slog(), Level: slogger.LevelWarn}
+	defer writer.Close()
+
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = writer
+
+	m.Logger.Debugf("EXEC: %v", cmd.String())
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf(`%v: %w`, cmd.String(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// preDrain prepares the cluster for the drain (used by kubernetes and nodemaintenance mode):
+// autoscaler scale down, kured lock, capacity, load balancer exclusion and readiness gates,
+// failures are logged and don't stop the drain, returns false if the drain was cancelled
+func (m *DrainManagerKubernetes) preDrain(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
+	m.disableAutoscalerScaleDown()
+
+	if m.Conf.Kubernetes.Kured.Mode != "" {
+		m.waitForKuredLock(ctx, event)
+	}
+
+	if m.Conf.Kubernetes.Capacity.Check {
+		m.ensureCapacity(ctx, event)
+	}
+
+	if m.Conf.Kubernetes.LoadBalancer.Exclude {
+		if !m.excludeFromLoadBalancers(ctx, m.Conf.Kubernetes.LoadBalancer.SettleTime) {
+			m.Logger.Warn("unable to exclude node from external load balancers, continuing with drain", slog.String("node", m.nodeName))
+		}
+	}
+
+	if m.Conf.Kubernetes.Pods.ReadinessGate != "" {
+		// stop reconciler before patching, it must not set the readiness gates to True again
+		m.readinessGateMaintenance.Store(true)
+		m.setPodReadinessGates("False", KubernetesReadinessGateReasonMaintenance, scheduledEventMessage(event))
+	}
+
+	return ctx.Err() == nil
+}
+
+// runPreEvictionHooks calls the pre-eviction hooks of the pods of the node (if enabled)
+func (m *DrainManagerKubernetes) runPreEvictionHooks(ctx context.Context, event *azuremetadata.AzureScheduledEvent) {
+	if !m.Conf.Kubernetes.Drain.Hooks.Enable {
+		return
+	}
+
+	if pods, err := m.nodePods(); err == nil {
+		m.runPodHooks(ctx, pods, event)
+	} else {
+		m.Logger.Error("unable to fetch pods for pre-eviction hooks", slog.String("node", m.nodeName), slog.Any("error", err))
+	}
+}
+
+// sleepContext waits for the duration, returns false if the context was cancelled before
+func sleepContext(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
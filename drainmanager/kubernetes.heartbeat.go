@@ -0,0 +1,163 @@
+package drainmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const (
+	KubernetesLeasePrefix = "azure-scheduledevents-"
+
+	KubernetesAnnotationHeartbeatVersion        = "webdevops.io/azure-scheduledevents-manager.version"
+	KubernetesAnnotationHeartbeatState          = "webdevops.io/azure-scheduledevents-manager.state"
+	KubernetesAnnotationHeartbeatLastApiSuccess = "webdevops.io/azure-scheduledevents-manager.last-imds-success"
+
+	// lease is stale after missing this number of scrapes
+	KubernetesLeaseDurationScrapes = 3
+
+	kubernetesMicroTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+	FleetStatusOk      = "ok"
+	FleetStatusStale   = "stale"
+	FleetStatusMissing = "missing"
+)
+
+type (
+	// HeartbeatStatus is the status of the manager published via heartbeat
+	HeartbeatStatus struct {
+		Version        string
+		State          string
+		LastApiSuccess time.Time
+	}
+
+	// FleetNodeStatus is the heartbeat status of the manager of one node
+	FleetNodeStatus struct {
+		Node           string     `json:"node"`
+		Status         string     `json:"status"`
+		Version        string     `json:"version,omitempty"`
+		State          string     `json:"state,omitempty"`
+		RenewTime      *time.Time `json:"renewTime,omitempty"`
+		LastApiSuccess string     `json:"lastImdsSuccess,omitempty"`
+	}
+
+	kubeLease struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			HolderIdentity       string `json:"holderIdentity"`
+			LeaseDurationSeconds int64  `json:"leaseDurationSeconds"`
+			RenewTime            string `json:"renewTime"`
+		} `json:"spec"`
+	}
+
+	kubeLeaseList struct {
+		Items []kubeLease `json:"items"`
+	}
+)
+
+// Heartbeat renews the Lease of the node with version, last IMDS success time and state of the manager
+func (m *DrainManagerKubernetes) Heartbeat(status HeartbeatStatus) {
+	conf := m.Conf.Kubernetes.Heartbeat
+	if !conf.Enable {
+		return
+	}
+
+	lastApiSuccess := ""
+	if !status.LastApiSuccess.IsZero() {
+		lastApiSuccess = status.LastApiSuccess.UTC().Format(time.RFC3339)
+	}
+
+	lease := map[string]interface{}{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata": map[string]interface{}{
+			"name":      KubernetesLeasePrefix + m.nodeName,
+			"namespace": conf.Namespace,
+			"labels": map[string]string{
+				KubernetesLabelName: m.nodeName,
+			},
+			"annotations": map[string]string{
+				KubernetesAnnotationHeartbeatVersion:        status.Version,
+				KubernetesAnnotationHeartbeatState:          status.State,
+				KubernetesAnnotationHeartbeatLastApiSuccess: lastApiSuccess,
+			},
+		},
+		"spec": map[string]interface{}{
+			"holderIdentity":       m.nodeName,
+			"leaseDurationSeconds": int64((KubernetesLeaseDurationScrapes * m.Conf.Scrape.Time).Seconds()),
+			"renewTime":            time.Now().UTC().Format(kubernetesMicroTimeFormat),
+		},
+	}
+
+	payload, err := json.Marshal(lease)
+	if err != nil {
+		m.Logger.Error("unable to build heartbeat lease", slog.Any("error", err))
+		return
+	}
+
+	// heartbeat is also written in dry-run mode
+	m.Logger.Debug("renew heartbeat lease", slog.String("node", m.nodeName), slog.String("state", status.State))
+	cmd := m.kubectlCommand("apply", "--filename=-")
+	cmd.Stdin = bytes.NewReader(payload)
+	if !m.runComand(cmd) {
+		m.Logger.Warn("unable to renew heartbeat lease", slog.String("node", m.nodeName))
+	}
+}
+
+// FleetStatus returns the heartbeat status of the managers of all nodes (matching the selector)
+func (m *DrainManagerKubernetes) FleetStatus(selector string) ([]FleetNodeStatus, error) {
+	args := []string{}
+	if selector != "" {
+		args = append(args, "--selector", selector)
+	}
+
+	nodeList := &kubeNodeList{}
+	if err := m.execGetJson(nodeList, "nodes", args...); err != nil {
+		return nil, err
+	}
+
+	leaseList := &kubeLeaseList{}
+	if err := m.execGetJson(leaseList, "leases.coordination.k8s.io", "--namespace", m.Conf.Kubernetes.Heartbeat.Namespace, "--selector", KubernetesLabelName); err != nil {
+		return nil, err
+	}
+
+	leases := map[string]kubeLease{}
+	for _, lease := range leaseList.Items {
+		leases[lease.Metadata.Labels[KubernetesLabelName]] = lease
+	}
+
+	ret := []FleetNodeStatus{}
+	for _, node := range nodeList.Items {
+		nodeStatus := FleetNodeStatus{
+			Node:   node.Metadata.Name,
+			Status: FleetStatusMissing,
+		}
+
+		if lease, exists := leases[node.Metadata.Name]; exists {
+			nodeStatus.Status = FleetStatusStale
+			nodeStatus.Version = lease.Metadata.Annotations[KubernetesAnnotationHeartbeatVersion]
+			nodeStatus.State = lease.Metadata.Annotations[KubernetesAnnotationHeartbeatState]
+			nodeStatus.LastApiSuccess = lease.Metadata.Annotations[KubernetesAnnotationHeartbeatLastApiSuccess]
+
+			if renewTime, err := time.Parse(kubernetesMicroTimeFormat, lease.Spec.RenewTime); err == nil {
+				nodeStatus.RenewTime = &renewTime
+				if time.Since(renewTime) <= time.Duration(lease.Spec.LeaseDurationSeconds)*time.Second {
+					nodeStatus.Status = FleetStatusOk
+				}
+			} else {
+				m.Logger.Warn(fmt.Sprintf("unable to parse renewTime of lease %v", lease.Metadata.Name), slog.Any("error", err))
+			}
+		}
+
+		ret = append(ret, nodeStatus)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Node < ret[j].Node
+	})
+
+	return ret, nil
+}
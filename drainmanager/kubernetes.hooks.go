@@ -0,0 +1,125 @@
+package drainmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesAnnotationHookPort    = "webdevops.io/azure-scheduledevents-manager.maintenance-hook-port"
+	KubernetesAnnotationHookPath    = "webdevops.io/azure-scheduledevents-manager.maintenance-hook-path"
+	KubernetesAnnotationHookTimeout = "webdevops.io/azure-scheduledevents-manager.maintenance-hook-timeout"
+
+	KubernetesHookRetryInterval = 2 * time.Second
+)
+
+// runPodHooks calls the pre-eviction hooks of all annotated pods in parallel and waits until all are finished
+func (m *DrainManagerKubernetes) runPodHooks(ctx context.Context, pods []kubePod, event *azuremetadata.AzureScheduledEvent) {
+	wg := sync.WaitGroup{}
+	for _, row := range pods {
+		pod := row
+		if _, exists := pod.Metadata.Annotations[KubernetesAnnotationHookPort]; !exists {
+			continue
+		}
+
+		if pod.isDaemonSetPod() || pod.isMirrorPod() || pod.isTerminated() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.runPodHook(ctx, &pod, event)
+		}()
+	}
+	wg.Wait()
+}
+
+// runPodHook sends the ScheduledEvent as JSON to the hook endpoint of the pod (http://podIP:port/path)
+// and retries until the endpoint answers with 2xx or the timeout is reached,
+// the timeout of the pod is limited by the hook timeout and NotBefore of the ScheduledEvent minus margin
+func (m *DrainManagerKubernetes) runPodHook(ctx context.Context, pod *kubePod, event *azuremetadata.AzureScheduledEvent) bool {
+	hookLogger := m.Logger.With(slog.String("namespace", pod.Metadata.Namespace), slog.String("pod", pod.Metadata.Name))
+
+	if pod.Status.PodIP == "" {
+		hookLogger.Warn("pod has no IP, skipping pre-eviction hook")
+		return false
+	}
+
+	port := pod.Metadata.Annotations[KubernetesAnnotationHookPort]
+	path := pod.Metadata.Annotations[KubernetesAnnotationHookPath]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	timeout := m.Conf.Kubernetes.Drain.Hooks.Timeout
+	if val, exists := pod.Metadata.Annotations[KubernetesAnnotationHookTimeout]; exists {
+		if podTimeout, err := time.ParseDuration(val); err == nil {
+			timeout = min(podTimeout, m.Conf.Kubernetes.Drain.Hooks.Timeout)
+		} else {
+			hookLogger.Warn("invalid pre-eviction hook timeout, using default", slog.String("timeout", val), slog.Any("error", err))
+		}
+	}
+
+	if remaining := time.Until(eventDeadline(event, m.Conf.Kubernetes.Drain.Hooks.Margin, timeout)); remaining < timeout {
+		timeout = max(remaining, 0)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		hookLogger.Error("unable to build pre-eviction hook payload", slog.Any("error", err))
+		return false
+	}
+
+	hookUrl := fmt.Sprintf("http://%v%v", net.JoinHostPort(pod.Status.PodIP, port), path)
+	hookLogger = hookLogger.With(slog.String("url", hookUrl), slog.Duration("timeout", timeout))
+	if m.Conf.Kubernetes.Drain.DryRun {
+		hookLogger.Info("skipping pre-eviction hook (dry-run)")
+		return true
+	}
+
+	hookLogger.Info("calling pre-eviction hook")
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := &http.Client{}
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hookUrl, bytes.NewReader(payload))
+		if err != nil {
+			hookLogger.Error("unable to build pre-eviction hook request", slog.Any("error", err))
+			return false
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close() // nolint:errcheck
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				hookLogger.Info("pre-eviction hook finished", slog.Int("statusCode", resp.StatusCode))
+				return true
+			}
+			hookLogger.Warn("pre-eviction hook failed", slog.Int("statusCode", resp.StatusCode))
+		} else {
+			hookLogger.Warn("pre-eviction hook failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			hookLogger.Warn("pre-eviction hook timed out, continuing with eviction")
+			m.recordEvent(KubernetesEventTypeWarning, "PreEvictionHookTimeout", fmt.Sprintf("pre-eviction hook of pod %v/%v timed out", pod.Metadata.Namespace, pod.Metadata.Name))
+			return false
+		case <-time.After(KubernetesHookRetryInterval):
+		}
+	}
+}
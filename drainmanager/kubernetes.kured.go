@@ -0,0 +1,183 @@
+package drainmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KuredLockModeRespect = "respect"
+	KuredLockModeAcquire = "acquire"
+
+	KuredLockPollInterval = 10 * time.Second
+)
+
+type (
+	// kuredLock is the lock format of kured (single lock and multi lock with maxOwners)
+	kuredLock struct {
+		NodeID   string `json:"nodeID,omitempty"`
+		Metadata struct {
+			Unschedulable bool `json:"unschedulable"`
+		} `json:"metadata"`
+		Created time.Time     `json:"created"`
+		TTL     time.Duration `json:"TTL"`
+
+		Locks []kuredLock `json:"locks,omitempty"`
+	}
+
+	kubeDaemonSet struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+	}
+)
+
+// owners returns the node names holding the lock (expired locks are ignored)
+func (l *kuredLock) owners() []string {
+	ret := []string{}
+
+	locks := append([]kuredLock{*l}, l.Locks...)
+	for _, lock := range locks {
+		if lock.NodeID == "" {
+			continue
+		}
+
+		if lock.TTL > 0 && time.Since(lock.Created) > lock.TTL {
+			continue
+		}
+
+		ret = append(ret, lock.NodeID)
+	}
+
+	return ret
+}
+
+// waitForKuredLock waits until the kured reboot lock is not held by other nodes (respect)
+// and takes it for this node (acquire), so Azure maintenance and kured reboots are serialized.
+// After the deadline (NotBefore of the ScheduledEvent minus margin) the drain continues anyway.
+func (m *DrainManagerKubernetes) waitForKuredLock(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
+	conf := m.Conf.Kubernetes.Kured
+
+	deadline := eventDeadline(event, conf.Margin, conf.Timeout)
+	waitLogger := m.Logger.With(slog.String("node", m.nodeName), slog.String("daemonset", fmt.Sprintf("%v/%v", conf.Namespace, conf.DaemonSet)), slog.String("mode", conf.Mode), slog.Time("deadline", deadline))
+
+	startTime := time.Now()
+	defer func() {
+		m.prometheus.kuredLockWait.With(prometheus.Labels{}).Set(time.Since(startTime).Seconds())
+	}()
+
+	for {
+		owners, resourceVersion, err := m.kuredLockOwners()
+		if err != nil {
+			waitLogger.Warn("unable to fetch kured lock", slog.Any("error", err))
+		} else {
+			otherOwners := stringListRemove(owners, m.nodeName)
+			if len(otherOwners) == 0 {
+				if conf.Mode != KuredLockModeAcquire || stringListContains(owners, m.nodeName) {
+					waitLogger.Info("kured lock is free", slog.Duration("waitTime", time.Since(startTime)))
+					return true
+				}
+
+				if m.acquireKuredLock(resourceVersion) {
+					waitLogger.Info("kured lock acquired", slog.Duration("waitTime", time.Since(startTime)))
+					return true
+				}
+				waitLogger.Warn("unable to acquire kured lock, retrying")
+			} else {
+				waitLogger.Info("waiting for kured lock", slog.Any("owners", otherOwners))
+			}
+		}
+
+		if time.Now().Add(KuredLockPollInterval).After(deadline) {
+			waitLogger.Warn("kured lock not available before deadline, continuing with drain")
+			m.recordEvent(KubernetesEventTypeWarning, "KuredLockTimeout", fmt.Sprintf("kured lock not available for %v", scheduledEventMessage(event)))
+			return false
+		}
+		if !sleepContext(ctx, KuredLockPollInterval) {
+			return false
+		}
+	}
+}
+
+// kuredLockOwners returns the current owners of the kured lock and the resourceVersion of the DaemonSet
+func (m *DrainManagerKubernetes) kuredLockOwners() ([]string, string, error) {
+	conf := m.Conf.Kubernetes.Kured
+
+	daemonSet := &kubeDaemonSet{}
+	if err := m.execGetJson(daemonSet, "daemonset", conf.DaemonSet, "--namespace", conf.Namespace); err != nil {
+		return nil, "", err
+	}
+
+	value := strings.TrimSpace(daemonSet.Metadata.Annotations[conf.Annotation])
+	if value == "" {
+		return []string{}, daemonSet.Metadata.ResourceVersion, nil
+	}
+
+	lock := &kuredLock{}
+	if err := json.Unmarshal([]byte(value), lock); err != nil {
+		return nil, "", fmt.Errorf(`unable to parse kured lock "%v": %w`, value, err)
+	}
+
+	return lock.owners(), daemonSet.Metadata.ResourceVersion, nil
+}
+
+// acquireKuredLock sets the kured lock annotation for this node (only if DaemonSet was not modified in the meantime)
+func (m *DrainManagerKubernetes) acquireKuredLock(resourceVersion string) bool {
+	conf := m.Conf.Kubernetes.Kured
+
+	lock := kuredLock{
+		NodeID:  m.nodeName,
+		Created: time.Now().UTC(),
+	}
+	lock.Metadata.Unschedulable = false
+
+	value, err := json.Marshal(lock)
+	if err != nil {
+		m.Logger.Error("unable to build kured lock", slog.Any("error", err))
+		return false
+	}
+
+	m.Logger.Info("acquire kured lock", slog.String("node", m.nodeName))
+	return m.exec(
+		"annotate", "daemonset", conf.DaemonSet,
+		"--namespace", conf.Namespace,
+		"--overwrite=true",
+		fmt.Sprintf("--resource-version=%v", resourceVersion),
+		fmt.Sprintf("%v=%v", conf.Annotation, string(value)),
+	)
+}
+
+// releaseKuredLock removes the kured lock annotation if it is held by this node
+func (m *DrainManagerKubernetes) releaseKuredLock() bool {
+	conf := m.Conf.Kubernetes.Kured
+
+	for try := 0; try < kubernetesAnnotationRetryCount; try++ {
+		owners, resourceVersion, err := m.kuredLockOwners()
+		if err != nil {
+			m.Logger.Error("unable to fetch kured lock", slog.Any("error", err))
+			return false
+		}
+
+		if !stringListContains(owners, m.nodeName) {
+			return true
+		}
+
+		m.Logger.Info("release kured lock", slog.String("node", m.nodeName))
+		if m.exec(
+			"annotate", "daemonset", conf.DaemonSet,
+			"--namespace", conf.Namespace,
+			fmt.Sprintf("--resource-version=%v", resourceVersion),
+			conf.Annotation+"-",
+		) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package drainmanager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	KubernetesLabelExcludeLoadBalancer          = "node.kubernetes.io/exclude-from-external-load-balancers"
+	KubernetesAnnotationExcludeLoadBalancerByUs = "webdevops.io/azure-scheduledevents-manager.exclude-from-external-load-balancers"
+)
+
+// excludeFromLoadBalancers sets the exclude-from-external-load-balancers label (if not already set by someone else)
+// and waits the settle time so the cloud provider can remove the node from the backend pools before eviction
+func (m *DrainManagerKubernetes) excludeFromLoadBalancers(ctx context.Context, settleTime time.Duration) bool {
+	node, err := m.getNode()
+	if err != nil {
+		m.Logger.Error("unable to fetch node", slog.String("node", m.nodeName), slog.Any("error", err))
+		return false
+	}
+
+	if _, exists := node.Metadata.Labels[KubernetesLabelExcludeLoadBalancer]; exists {
+		if _, byUs := node.Metadata.Annotations[KubernetesAnnotationExcludeLoadBalancerByUs]; !byUs {
+			m.Logger.Debug("node already excluded from external load balancers by someone else", slog.String("node", m.nodeName))
+		}
+		return true
+	}
+
+	m.Logger.Info("exclude node from external load balancers", slog.String("node", m.nodeName))
+	if !m.exec("annotate", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=true", KubernetesAnnotationExcludeLoadBalancerByUs)) {
+		return false
+	}
+
+	if !m.exec("label", "node", m.nodeName, "--overwrite=true", fmt.Sprintf("%v=true", KubernetesLabelExcludeLoadBalancer)) {
+		return false
+	}
+
+	if settleTime > 0 && !m.Conf.Kubernetes.Drain.DryRun {
+		m.Logger.Info("wait for load balancer backend pool update", slog.String("node", m.nodeName), slog.Duration("waitTime", settleTime))
+		sleepContext(ctx, settleTime)
+	}
+
+	return true
+}
+
+// includeInLoadBalancers removes the exclude-from-external-load-balancers label, but only if it was set by this manager
+func (m *DrainManagerKubernetes) includeInLoadBalancers(node *kubeNode) bool {
+	if _, byUs := node.Metadata.Annotations[KubernetesAnnotationExcludeLoadBalancerByUs]; !byUs {
+		return true
+	}
+
+	m.Logger.Info("include node in external load balancers", slog.String("node", m.nodeName))
+	if !m.exec("label", "node", m.nodeName, KubernetesLabelExcludeLoadBalancer+"-") {
+		return false
+	}
+
+	return m.exec("annotate", "node", m.nodeName, KubernetesAnnotationExcludeLoadBalancerByUs+"-")
+}
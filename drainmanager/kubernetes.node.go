@@ -0,0 +1,117 @@
+package drainmanager
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesProviderIdPrefix = "azure://"
+)
+
+// DiscoverNodeName finds the Kubernetes node of the current VM by matching spec.providerID
+// against the resourceId (or status.nodeInfo.systemUUID against the vmId) from instance metadata
+func (m *DrainManagerKubernetes) DiscoverNodeName(instance *azuremetadata.AzureMetadataInstanceResponse) (string, error) {
+	if instance == nil {
+		return "", errors.New(`unable to discover kubernetes node name without instance metadata`)
+	}
+
+	nodeList := &kubeNodeList{}
+	if err := m.execGetJson(nodeList, "nodes"); err != nil {
+		return "", fmt.Errorf(`unable to list kubernetes nodes: %w`, err)
+	}
+
+	matches := []string{}
+	for _, node := range nodeList.Items {
+		if matched, _ := nodeMatchesInstance(&node, instance); matched {
+			matches = append(matches, node.Metadata.Name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf(`no kubernetes node found for VM (resourceId: %v, vmId: %v)`, instance.Compute.ResourceID, instance.Compute.VMID)
+	case 1:
+		m.Logger.Info("discovered kubernetes node", slog.String("node", matches[0]), slog.String("resourceId", instance.Compute.ResourceID))
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf(`multiple kubernetes nodes found for VM (resourceId: %v): %v`, instance.Compute.ResourceID, strings.Join(matches, ", "))
+	}
+}
+
+// VerifyNode checks if the configured Kubernetes node belongs to the current VM
+func (m *DrainManagerKubernetes) VerifyNode(instance *azuremetadata.AzureMetadataInstanceResponse) error {
+	node, err := m.getNode()
+	if err != nil {
+		return err
+	}
+	m.setNodeUid(node.Metadata.UID)
+
+	matched, verifiable := nodeMatchesInstance(node, instance)
+	if !verifiable {
+		m.Logger.Warn("unable to verify kubernetes node, neither providerID nor systemUUID can be compared", slog.String("node", m.nodeName))
+		return nil
+	}
+
+	if !matched {
+		return fmt.Errorf(
+			`kubernetes node "%v" (providerID: %v, systemUUID: %v) does not belong to this VM (resourceId: %v, vmId: %v)`,
+			m.nodeName,
+			node.Spec.ProviderID,
+			node.Status.NodeInfo.SystemUUID,
+			instance.Compute.ResourceID,
+			instance.Compute.VMID,
+		)
+	}
+
+	return nil
+}
+
+// nodeMatchesInstance returns if the node matches the instance and if the match could be verified at all
+func nodeMatchesInstance(node *kubeNode, instance *azuremetadata.AzureMetadataInstanceResponse) (matched bool, verifiable bool) {
+	if node.Spec.ProviderID != "" && instance.Compute.ResourceID != "" {
+		return normalizeResourceId(node.Spec.ProviderID) == normalizeResourceId(instance.Compute.ResourceID), true
+	}
+
+	if node.Status.NodeInfo.SystemUUID != "" && instance.Compute.VMID != "" {
+		systemUuid := strings.ToLower(node.Status.NodeInfo.SystemUUID)
+		vmId := strings.ToLower(instance.Compute.VMID)
+		return systemUuid == vmId || systemUuid == swapUuidByteOrder(vmId), true
+	}
+
+	return false, false
+}
+
+func normalizeResourceId(val string) string {
+	val = strings.TrimPrefix(strings.ToLower(val), KubernetesProviderIdPrefix)
+	val = "/" + strings.Trim(val, "/")
+	return val
+}
+
+// swapUuidByteOrder converts between the mixed-endian SMBIOS representation and the vmId representation
+// of the first three UUID fields (Azure Gen1 VMs report the SMBIOS UUID byte swapped)
+func swapUuidByteOrder(val string) string {
+	parts := strings.Split(val, "-")
+	if len(parts) != 5 {
+		return val
+	}
+
+	for i := 0; i < 3; i++ {
+		part := parts[i]
+		if len(part)%2 != 0 {
+			return val
+		}
+
+		swapped := ""
+		for pos := len(part); pos > 0; pos -= 2 {
+			swapped += part[pos-2 : pos]
+		}
+		parts[i] = swapped
+	}
+
+	return strings.Join(parts, "-")
+}
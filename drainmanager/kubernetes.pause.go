@@ -0,0 +1,75 @@
+package drainmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type (
+	kubeConfigMap struct {
+		Metadata kubeObjectMeta    `json:"metadata"`
+		Data     map[string]string `json:"data"`
+	}
+)
+
+// Paused checks the pause switches (cluster-wide ConfigMap key and node annotation),
+// if a switch can't be fetched the last known state is kept and the error is returned
+func (m *DrainManagerKubernetes) Paused() (bool, string, error) {
+	paused, reason, err := m.checkPauseSwitches()
+
+	m.pausedLock.Lock()
+	defer m.pausedLock.Unlock()
+
+	if err != nil {
+		m.Logger.Warn("unable to check pause switches, keeping last known state", slog.String("node", m.nodeName), slog.Bool("paused", m.paused), slog.Any("error", err))
+		return m.paused, m.pausedReason, err
+	}
+
+	m.paused, m.pausedReason = paused, reason
+	return paused, reason, nil
+}
+
+// checkPauseSwitches returns the state of the pause switches, errors are only returned if no switch is paused
+func (m *DrainManagerKubernetes) checkPauseSwitches() (bool, string, error) {
+	conf := m.Conf.Kubernetes.Pause
+	errs := []error{}
+
+	if conf.ConfigMap != "" {
+		namespace, name, found := strings.Cut(conf.ConfigMap, "/")
+		if !found {
+			namespace, name = "default", conf.ConfigMap
+		}
+
+		output, err := m.runComandOutput(m.kubectlCommand("get", "configmap", name, "--namespace", namespace, "--ignore-not-found=true", "--output=json"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf(`unable to fetch pause ConfigMap "%v": %w`, conf.ConfigMap, err))
+		} else if len(output) > 0 {
+			configMap := &kubeConfigMap{}
+			if err := json.Unmarshal(output, configMap); err != nil {
+				errs = append(errs, fmt.Errorf(`unable to parse pause ConfigMap "%v": %w`, conf.ConfigMap, err))
+			} else if isPauseValue(configMap.Data[conf.Key]) {
+				return true, fmt.Sprintf(`ConfigMap %v key "%v"`, conf.ConfigMap, conf.Key), nil
+			}
+		}
+	}
+
+	if conf.Annotation != "" {
+		node, err := m.getNode()
+		if err != nil {
+			errs = append(errs, fmt.Errorf(`unable to fetch node "%v" for pause annotation: %w`, m.nodeName, err))
+		} else if isPauseValue(node.Metadata.Annotations[conf.Annotation]) {
+			return true, fmt.Sprintf(`node annotation "%v"`, conf.Annotation), nil
+		}
+	}
+
+	return false, "", errors.Join(errs...)
+}
+
+func isPauseValue(val string) bool {
+	paused, err := strconv.ParseBool(strings.TrimSpace(val))
+	return err == nil && paused
+}
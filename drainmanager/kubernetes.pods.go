@@ -0,0 +1,144 @@
+package drainmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesAnnotationPodEventId   = "webdevops.io/azure-scheduledevents-manager.event-id"
+	KubernetesAnnotationPodEventType = "webdevops.io/azure-scheduledevents-manager.event-type"
+	KubernetesAnnotationPodNotBefore = "webdevops.io/azure-scheduledevents-manager.not-before"
+
+	KubernetesReadinessGateReasonMaintenance = "ScheduledMaintenance"
+	KubernetesReadinessGateReasonCleared     = "NoScheduledMaintenance"
+)
+
+// annotatePods annotates all pods of the node with EventId, EventType and NotBefore of the ScheduledEvent
+func (m *DrainManagerKubernetes) annotatePods(event *azuremetadata.AzureScheduledEvent) {
+	notBefore := event.NotBefore
+	if notBefore == "" {
+		notBefore = "now"
+	}
+
+	m.Logger.Info("annotate pods with maintenance notice", slog.String("node", m.nodeName), slog.String("eventID", event.EventId))
+	m.annotateNodePods(
+		"--overwrite=true",
+		fmt.Sprintf("%v=%v", KubernetesAnnotationPodEventId, event.EventId),
+		fmt.Sprintf("%v=%v", KubernetesAnnotationPodEventType, event.EventType),
+		fmt.Sprintf("%v=%v", KubernetesAnnotationPodNotBefore, notBefore),
+	)
+}
+
+// unannotatePods removes the maintenance notice annotations from all pods of the node
+func (m *DrainManagerKubernetes) unannotatePods() {
+	m.Logger.Info("remove maintenance notice from pods", slog.String("node", m.nodeName))
+	m.annotateNodePods(
+		KubernetesAnnotationPodEventId+"-",
+		KubernetesAnnotationPodEventType+"-",
+		KubernetesAnnotationPodNotBefore+"-",
+	)
+}
+
+// annotateNodePods runs kubectl annotate for all pods of the node (one call per namespace)
+func (m *DrainManagerKubernetes) annotateNodePods(args ...string) {
+	pods, err := m.nodePods()
+	if err != nil {
+		m.Logger.Error("unable to fetch pods", slog.String("node", m.nodeName), slog.Any("error", err))
+		return
+	}
+
+	namespaces := []string{}
+	for _, pod := range pods {
+		if pod.isMirrorPod() || pod.isTerminated() {
+			continue
+		}
+
+		if !stringListContains(namespaces, pod.Metadata.Namespace) {
+			namespaces = append(namespaces, pod.Metadata.Namespace)
+		}
+	}
+
+	for _, namespace := range namespaces {
+		kubectlArgs := []string{"annotate", "pods", "--all", "--namespace", namespace, fmt.Sprintf("--field-selector=spec.nodeName=%v", m.nodeName)}
+		kubectlArgs = append(kubectlArgs, args...)
+		if !m.exec(kubectlArgs...) {
+			m.Logger.Warn("unable to annotate pods", slog.String("node", m.nodeName), slog.String("namespace", namespace))
+		}
+	}
+}
+
+// startReadinessGateReconciler periodically sets the readiness gate condition of new pods to True
+// (pods with readiness gates are not ready until the condition exists) unless a drain is in progress
+func (m *DrainManagerKubernetes) startReadinessGateReconciler() {
+	go func() {
+		for {
+			time.Sleep(m.Conf.Scrape.Time)
+			if !m.readinessGateMaintenance.Load() {
+				m.setPodReadinessGates("True", KubernetesReadinessGateReasonCleared, "no Azure ScheduledEvent for this node")
+			}
+		}
+	}()
+}
+
+// setPodReadinessGates sets the configured readiness gate condition of all pods of the node which declare it
+// in spec.readinessGates, pods with a readiness gate condition "False" are removed from service endpoints.
+// Calls are serialized and setting "True" stops as soon as a maintenance is started (readinessGateMaintenance)
+func (m *DrainManagerKubernetes) setPodReadinessGates(status, reason, message string) {
+	m.readinessGateLock.Lock()
+	defer m.readinessGateLock.Unlock()
+
+	conditionType := m.Conf.Kubernetes.Pods.ReadinessGate
+
+	pods, err := m.nodePods()
+	if err != nil {
+		m.Logger.Error("unable to fetch pods", slog.String("node", m.nodeName), slog.Any("error", err))
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []map[string]interface{}{
+				{
+					"type":               conditionType,
+					"status":             status,
+					"reason":             reason,
+					"message":            message,
+					"lastTransitionTime": now,
+				},
+			},
+		},
+	}
+
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		m.Logger.Error("unable to build readiness gate patch", slog.Any("error", err))
+		return
+	}
+
+	for _, pod := range pods {
+		if status == "True" && m.readinessGateMaintenance.Load() {
+			m.Logger.Info("maintenance started, stop setting pod readiness gates", slog.String("node", m.nodeName))
+			return
+		}
+
+		if !pod.hasReadinessGate(conditionType) || pod.isTerminated() {
+			continue
+		}
+
+		if pod.conditionStatus(conditionType) == status {
+			continue
+		}
+
+		podLogger := m.Logger.With(slog.String("namespace", pod.Metadata.Namespace), slog.String("pod", pod.Metadata.Name))
+		podLogger.Info("set pod readiness gate", slog.String("condition", conditionType), slog.String("status", status))
+		if !m.exec("patch", "pod", pod.Metadata.Name, "--namespace", pod.Metadata.Namespace, "--subresource=status", "--type=strategic", fmt.Sprintf("--patch=%s", payload)) {
+			podLogger.Warn("unable to set pod readiness gate")
+		}
+	}
+}
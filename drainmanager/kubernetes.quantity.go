@@ -0,0 +1,55 @@
+package drainmanager
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	kubeQuantitySuffixList = []struct {
+		suffix     string
+		multiplier float64
+	}{
+		// binary suffixes first, "Mi" must not be parsed as "M"
+		{"Ki", 1 << 10},
+		{"Mi", 1 << 20},
+		{"Gi", 1 << 30},
+		{"Ti", 1 << 40},
+		{"Pi", 1 << 50},
+		{"Ei", 1 << 60},
+		{"n", 1e-9},
+		{"u", 1e-6},
+		{"m", 1e-3},
+		{"k", 1e3},
+		{"M", 1e6},
+		{"G", 1e9},
+		{"T", 1e12},
+		{"P", 1e15},
+		{"E", 1e18},
+	}
+)
+
+// parseQuantity parses Kubernetes resource quantities (eg. "100m", "1.5", "512Mi", "1e3") into float values,
+// invalid or empty quantities are parsed as 0
+func parseQuantity(val string) float64 {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0
+	}
+
+	multiplier := float64(1)
+	for _, row := range kubeQuantitySuffixList {
+		if strings.HasSuffix(val, row.suffix) {
+			val = strings.TrimSuffix(val, row.suffix)
+			multiplier = row.multiplier
+			break
+		}
+	}
+
+	ret, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0
+	}
+
+	return ret * multiplier
+}
@@ -0,0 +1,437 @@
+package drainmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	DrainReportStatusReady    = "ready"
+	DrainReportStatusNotReady = "notReady"
+	DrainReportStatusPending  = "pending"
+	DrainReportStatusMissing  = "missing"
+	DrainReportStatusDeleted  = "deleted"
+
+	DrainReportStatePending  = "pending"
+	DrainReportStateFinished = "finished"
+
+	DrainReportNamePrefix  = "azure-scheduledevents-report-"
+	DrainReportKeyJson     = "report.json"
+	DrainReportKeyMarkdown = "report.md"
+
+	KubernetesLabelDrainReport = "webdevops.io/azure-scheduledevents-manager.drain-report"
+
+	DrainReportPollInterval = 10 * time.Second
+)
+
+var (
+	drainReportNameCleanup = regexp.MustCompile(`[^a-z0-9.-]+`)
+)
+
+type (
+	// DrainReport contains the pods of the node before the drain and where their replacements landed
+	DrainReport struct {
+		Node            string                             `json:"node"`
+		Event           *azuremetadata.AzureScheduledEvent `json:"event"`
+		StartTime       time.Time                          `json:"startTime"`
+		DrainFinishTime time.Time                          `json:"drainFinishTime,omitempty"`
+		FinishTime      time.Time                          `json:"finishTime,omitempty"`
+		Success         bool                               `json:"success"`
+		Pods            []DrainReportPod                   `json:"pods"`
+
+		// pod uids per controller before drain, used to detect replacements (persisted for reports finished after reboot)
+		KnownPods map[string][]string `json:"knownPods,omitempty"`
+	}
+
+	DrainReportPod struct {
+		Namespace           string `json:"namespace"`
+		Name                string `json:"name"`
+		OwnerKind           string `json:"ownerKind,omitempty"`
+		OwnerName           string `json:"ownerName,omitempty"`
+		PodDisruptionBudget string `json:"podDisruptionBudget,omitempty"`
+		Status              string `json:"status"`
+		ReplacementName     string `json:"replacementName,omitempty"`
+		ReplacementNode     string `json:"replacementNode,omitempty"`
+		OwnerUID            string `json:"ownerUID,omitempty"`
+	}
+
+	kubeLabelSelector struct {
+		MatchLabels      map[string]string `json:"matchLabels"`
+		MatchExpressions []struct {
+			Key      string   `json:"key"`
+			Operator string   `json:"operator"`
+			Values   []string `json:"values"`
+		} `json:"matchExpressions"`
+	}
+
+	kubePodDisruptionBudget struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			Selector *kubeLabelSelector `json:"selector"`
+		} `json:"spec"`
+	}
+
+	kubeConfigMapList struct {
+		Items []kubeConfigMap `json:"items"`
+	}
+
+	kubePodDisruptionBudgetList struct {
+		Items []kubePodDisruptionBudget `json:"items"`
+	}
+)
+
+// matches checks if the labels match the label selector (empty selector matches everything)
+func (s *kubeLabelSelector) matches(labels map[string]string) bool {
+	for key, value := range s.MatchLabels {
+		if labels[key] != value {
+			return false
+		}
+	}
+
+	for _, expression := range s.MatchExpressions {
+		value, exists := labels[expression.Key]
+		switch expression.Operator {
+		case "In":
+			if !exists || !stringListContains(expression.Values, value) {
+				return false
+			}
+		case "NotIn":
+			if exists && stringListContains(expression.Values, value) {
+				return false
+			}
+		case "Exists":
+			if !exists {
+				return false
+			}
+		case "DoesNotExist":
+			if exists {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// startDrainReport takes a snapshot of all pods of the node (without DaemonSet and mirror pods) before eviction
+func (m *DrainManagerKubernetes) startDrainReport(event *azuremetadata.AzureScheduledEvent) *DrainReport {
+	report := &DrainReport{
+		Node:      m.nodeName,
+		Event:     event,
+		StartTime: time.Now(),
+		Pods:      []DrainReportPod{},
+		KnownPods: map[string][]string{},
+	}
+
+	podList := &kubePodList{}
+	if err := m.execGetJson(podList, "pods", "--all-namespaces"); err != nil {
+		m.Logger.Error("unable to fetch pods for drain report", slog.Any("error", err))
+		return report
+	}
+
+	pdbList := &kubePodDisruptionBudgetList{}
+	if err := m.execGetJson(pdbList, "poddisruptionbudgets.policy", "--all-namespaces"); err != nil {
+		m.Logger.Warn("unable to fetch PodDisruptionBudgets for drain report", slog.Any("error", err))
+	}
+
+	for _, pod := range podList.Items {
+		ownerRef := pod.controllerRef()
+		if ownerRef != nil {
+			report.KnownPods[ownerRef.UID] = append(report.KnownPods[ownerRef.UID], pod.Metadata.UID)
+		}
+
+		if pod.Spec.NodeName != m.nodeName || pod.isDaemonSetPod() || pod.isMirrorPod() || pod.isTerminated() {
+			continue
+		}
+
+		reportPod := DrainReportPod{
+			Namespace: pod.Metadata.Namespace,
+			Name:      pod.Metadata.Name,
+		}
+
+		if ownerRef != nil {
+			reportPod.OwnerKind = ownerRef.Kind
+			reportPod.OwnerName = ownerRef.Name
+			reportPod.OwnerUID = ownerRef.UID
+		}
+
+		for _, pdb := range pdbList.Items {
+			if pdb.Metadata.Namespace == pod.Metadata.Namespace && pdb.Spec.Selector != nil && pdb.Spec.Selector.matches(pod.Metadata.Labels) {
+				reportPod.PodDisruptionBudget = pdb.Metadata.Name
+				break
+			}
+		}
+
+		report.Pods = append(report.Pods, reportPod)
+	}
+
+	return report
+}
+
+// saveDrainReport persists the report as ConfigMap in the report namespace (event history),
+// a pending report survives the reboot of the node and is finished after the maintenance
+func (m *DrainManagerKubernetes) saveDrainReport(report *DrainReport) {
+	jsonReport, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		m.Logger.Error("unable to build drain report", slog.String("node", report.Node), slog.Any("error", err))
+		return
+	}
+
+	state := DrainReportStatePending
+	data := map[string]string{
+		DrainReportKeyJson: string(jsonReport),
+	}
+	if !report.FinishTime.IsZero() {
+		state = DrainReportStateFinished
+		data[DrainReportKeyMarkdown] = report.Markdown()
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      drainReportName(report),
+			"namespace": m.Conf.Kubernetes.Drain.Report.Namespace,
+			"labels": map[string]string{
+				KubernetesLabelName:        report.Node,
+				KubernetesLabelDrainReport: state,
+			},
+		},
+		"data": data,
+	})
+	if err != nil {
+		m.Logger.Error("unable to build drain report ConfigMap", slog.String("node", report.Node), slog.Any("error", err))
+		return
+	}
+
+	if !m.execWithInput(payload, "apply", "--server-side=true", "--force-conflicts=true", "--filename=-") {
+		m.Logger.Error("unable to save drain report", slog.String("node", report.Node), slog.String("name", drainReportName(report)))
+	}
+}
+
+// finishDrainReports finishes the pending drain reports of the node (after the maintenance, eg. after reboot)
+// and of nodes which are gone (eg. terminated or scaled in), deletes finished reports older than the retention
+// and returns the summaries of the finished reports of the node
+func (m *DrainManagerKubernetes) finishDrainReports() []string {
+	summaries := []string{}
+
+	list := &kubeConfigMapList{}
+	if err := m.execGetJson(list, "configmaps", "--namespace", m.Conf.Kubernetes.Drain.Report.Namespace, "--selector", KubernetesLabelDrainReport); err != nil {
+		m.Logger.Warn("unable to fetch drain reports", slog.Any("error", err))
+		return summaries
+	}
+
+	for _, configMap := range list.Items {
+		report := &DrainReport{}
+		if err := json.Unmarshal([]byte(configMap.Data[DrainReportKeyJson]), report); err != nil || report.Event == nil {
+			m.Logger.Warn("unable to parse drain report", slog.String("name", configMap.Metadata.Name), slog.Any("error", err))
+			continue
+		}
+
+		if configMap.Metadata.Labels[KubernetesLabelDrainReport] == DrainReportStateFinished {
+			if time.Since(report.FinishTime) > m.Conf.Kubernetes.Drain.Report.Retention {
+				m.Logger.Info("delete drain report", slog.String("name", configMap.Metadata.Name))
+				m.exec("delete", "configmap", configMap.Metadata.Name, "--namespace", m.Conf.Kubernetes.Drain.Report.Namespace, "--ignore-not-found=true")
+			}
+			continue
+		}
+
+		switch {
+		case report.Node == m.nodeName:
+			m.finishDrainReport(report, true)
+			summaries = append(summaries, report.Summary())
+		case m.nodeExists(report.Node):
+			// finished by the manager of the node after its maintenance
+			continue
+		default:
+			// node is gone, finish report without waiting for replacements
+			m.finishDrainReport(report, false)
+		}
+	}
+
+	return summaries
+}
+
+// finishDrainReport waits until all evicted pods have ready replacements (or the wait time after the drain is over),
+// saves the finished report (JSON and Markdown) and records the summary as Kubernetes Event and ScheduledEvent status,
+// reports of other nodes are finished without waiting
+func (m *DrainManagerKubernetes) finishDrainReport(report *DrainReport, wait bool) {
+	deadline := report.DrainFinishTime.Add(m.Conf.Kubernetes.Drain.Report.Wait)
+	for {
+		m.updateDrainReport(report)
+
+		if report.count(DrainReportStatusReady)+report.count(DrainReportStatusDeleted) == len(report.Pods) || m.Conf.Kubernetes.Drain.DryRun {
+			break
+		}
+
+		if !wait || time.Now().Add(DrainReportPollInterval).After(deadline) {
+			break
+		}
+		time.Sleep(DrainReportPollInterval)
+	}
+	report.FinishTime = time.Now()
+
+	m.saveDrainReport(report)
+
+	summary := report.Summary()
+	m.Logger.Info("drain report", slog.String("node", report.Node), slog.String("summary", summary))
+	if report.Node == m.nodeName {
+		m.recordEvent(KubernetesEventTypeNormal, "DrainReport", summary)
+		m.setScheduledEventStatus(report.Event, map[string]interface{}{
+			"report": summary,
+		})
+	}
+}
+
+// nodeExists checks if the node still exists, errors are treated as existing to not finish reports too early
+func (m *DrainManagerKubernetes) nodeExists(name string) bool {
+	output, err := m.runComandOutput(m.kubectlCommand("get", "node", name, "--ignore-not-found=true", "--output=name"))
+	if err != nil {
+		m.Logger.Warn("unable to fetch node", slog.String("node", name), slog.Any("error", err))
+		return true
+	}
+	return len(bytes.TrimSpace(output)) > 0
+}
+
+// updateDrainReport searches the replacements of the evicted pods (new pods of the same controller)
+func (m *DrainManagerKubernetes) updateDrainReport(report *DrainReport) {
+	podList := &kubePodList{}
+	if err := m.execGetJson(podList, "pods", "--all-namespaces"); err != nil {
+		m.Logger.Warn("unable to fetch pods for drain report", slog.Any("error", err))
+		return
+	}
+
+	replacements := map[string][]kubePod{}
+	for _, pod := range podList.Items {
+		ownerRef := pod.controllerRef()
+		if ownerRef == nil || pod.isTerminated() || pod.Spec.NodeName == m.nodeName {
+			continue
+		}
+
+		if stringListContains(report.KnownPods[ownerRef.UID], pod.Metadata.UID) {
+			continue
+		}
+
+		replacements[ownerRef.UID] = append(replacements[ownerRef.UID], pod)
+	}
+
+	used := map[string]bool{}
+	for i := range report.Pods {
+		reportPod := &report.Pods[i]
+		reportPod.ReplacementName = ""
+		reportPod.ReplacementNode = ""
+
+		if reportPod.OwnerUID == "" {
+			// pods without controller are not recreated
+			reportPod.Status = DrainReportStatusDeleted
+			continue
+		}
+
+		var replacement *kubePod
+		for j, pod := range replacements[reportPod.OwnerUID] {
+			if used[pod.Metadata.UID] {
+				continue
+			}
+
+			// StatefulSet pods keep their name
+			if reportPod.OwnerKind == "StatefulSet" && pod.Metadata.Name != reportPod.Name {
+				continue
+			}
+
+			replacement = &replacements[reportPod.OwnerUID][j]
+			used[pod.Metadata.UID] = true
+			break
+		}
+
+		switch {
+		case replacement == nil:
+			reportPod.Status = DrainReportStatusMissing
+		case replacement.Spec.NodeName == "":
+			reportPod.Status = DrainReportStatusPending
+			reportPod.ReplacementName = replacement.Metadata.Name
+		case replacement.isReady():
+			reportPod.Status = DrainReportStatusReady
+			reportPod.ReplacementName = replacement.Metadata.Name
+			reportPod.ReplacementNode = replacement.Spec.NodeName
+		default:
+			reportPod.Status = DrainReportStatusNotReady
+			reportPod.ReplacementName = replacement.Metadata.Name
+			reportPod.ReplacementNode = replacement.Spec.NodeName
+		}
+	}
+}
+
+func (r *DrainReport) count(status string) int {
+	ret := 0
+	for _, pod := range r.Pods {
+		if pod.Status == status {
+			ret++
+		}
+	}
+	return ret
+}
+
+// Summary returns a one line summary of the report
+func (r *DrainReport) Summary() string {
+	result := "finished"
+	if !r.Success {
+		result = "failed"
+	}
+
+	return fmt.Sprintf(
+		"drain report of node %v for Azure ScheduledEvent %v (drain %v): %v pods, %v ready, %v not ready, %v pending, %v missing, %v deleted",
+		r.Node,
+		r.Event.EventId,
+		result,
+		len(r.Pods),
+		r.count(DrainReportStatusReady),
+		r.count(DrainReportStatusNotReady),
+		r.count(DrainReportStatusPending),
+		r.count(DrainReportStatusMissing),
+		r.count(DrainReportStatusDeleted),
+	)
+}
+
+// Markdown returns the report as Markdown document
+func (r *DrainReport) Markdown() string {
+	md := []string{
+		fmt.Sprintf("# Drain report of node %v", r.Node),
+		"",
+		fmt.Sprintf("- ScheduledEvent: %v (%v, NotBefore: %v)", r.Event.EventId, r.Event.EventType, r.Event.NotBefore),
+		fmt.Sprintf("- Drain start: %v", r.StartTime.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("- Drain finish: %v", r.DrainFinishTime.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("- Report finish: %v", r.FinishTime.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("- Drain successful: %v", r.Success),
+		"",
+		r.Summary(),
+		"",
+		"| Namespace | Pod | Owner | PodDisruptionBudget | Status | Replacement | Node |",
+		"|-----------|-----|-------|---------------------|--------|-------------|------|",
+	}
+
+	for _, pod := range r.Pods {
+		owner := ""
+		if pod.OwnerKind != "" {
+			owner = fmt.Sprintf("%v/%v", pod.OwnerKind, pod.OwnerName)
+		}
+
+		md = append(md, fmt.Sprintf("| %v | %v | %v | %v | %v | %v | %v |", pod.Namespace, pod.Name, owner, pod.PodDisruptionBudget, pod.Status, pod.ReplacementName, pod.ReplacementNode))
+	}
+
+	return strings.Join(md, "\n") + "\n"
+}
+
+// drainReportName returns the ConfigMap name of the report (<node>-<eventid>)
+func drainReportName(report *DrainReport) string {
+	name := strings.ToLower(fmt.Sprintf("%v%v-%v", DrainReportNamePrefix, report.Node, report.Event.EventId))
+	return strings.Trim(drainReportNameCleanup.ReplaceAllString(name, "-"), "-.")
+}
@@ -0,0 +1,50 @@
+package drainmanager
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+// Terminate cleans up the node after drain and approval of a Terminate ScheduledEvent (VM will not come back):
+// releases autoscaler annotations of peers and kured lock, runs the deregistration command and taints or deletes the node
+func (m *DrainManagerKubernetes) Terminate(event *azuremetadata.AzureScheduledEvent) bool {
+	conf := m.Conf.Kubernetes.Terminate
+	ret := true
+
+	// node will be gone, peers must not stay annotated by this manager
+	if !m.enableAutoscalerScaleDown() {
+		ret = false
+	}
+
+	if m.Conf.Kubernetes.Kured.Mode == KuredLockModeAcquire && !m.releaseKuredLock() {
+		ret = false
+	}
+
+	if m.Conf.Drain.Terminate.Cmd != "" {
+		m.Logger.Info("run deregistration command", slog.String("node", m.nodeName))
+		if !execShellCommand(m.Logger, m.Conf.Drain.Terminate.Cmd, event) {
+			ret = false
+		}
+	}
+
+	if conf.Taint != "" {
+		m.Logger.Info("taint terminated node", slog.String("node", m.nodeName), slog.String("taint", conf.Taint))
+		if !m.exec("taint", "node", m.nodeName, "--overwrite=true", conf.Taint) {
+			ret = false
+		}
+	}
+
+	if conf.DeleteNode {
+		m.recordEvent(KubernetesEventTypeNormal, "NodeDeleted", fmt.Sprintf("deleting node for %v", scheduledEventMessage(event)))
+		m.Logger.Info("delete node", slog.String("node", m.nodeName))
+		if !m.exec("delete", "node", m.nodeName, "--ignore-not-found=true", "--wait=false") {
+			ret = false
+		}
+	} else if conf.Taint != "" {
+		m.recordEvent(KubernetesEventTypeNormal, "NodeTerminated", fmt.Sprintf("node tainted with %v for %v", conf.Taint, scheduledEventMessage(event)))
+	}
+
+	return ret
+}
@@ -0,0 +1,221 @@
+package drainmanager
+
+type (
+	kubeObjectMeta struct {
+		Name            string               `json:"name"`
+		Namespace       string               `json:"namespace,omitempty"`
+		UID             string               `json:"uid,omitempty"`
+		ResourceVersion string               `json:"resourceVersion,omitempty"`
+		Labels          map[string]string    `json:"labels,omitempty"`
+		Annotations     map[string]string    `json:"annotations,omitempty"`
+		OwnerReferences []kubeOwnerReference `json:"ownerReferences,omitempty"`
+	}
+
+	kubeOwnerReference struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		UID        string `json:"uid"`
+		Controller *bool  `json:"controller,omitempty"`
+	}
+
+	kubeCondition struct {
+		Type   string `json:"type"`
+		Status string `json:"status"`
+		Reason string `json:"reason,omitempty"`
+	}
+
+	kubeTaint struct {
+		Key    string `json:"key"`
+		Value  string `json:"value,omitempty"`
+		Effect string `json:"effect"`
+	}
+
+	kubeNode struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			ProviderID    string      `json:"providerID"`
+			Unschedulable bool        `json:"unschedulable"`
+			Taints        []kubeTaint `json:"taints"`
+		} `json:"spec"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+			Conditions  []kubeCondition   `json:"conditions"`
+			NodeInfo    struct {
+				SystemUUID string `json:"systemUUID"`
+			} `json:"nodeInfo"`
+		} `json:"status"`
+	}
+
+	kubeNodeList struct {
+		Items []kubeNode `json:"items"`
+	}
+
+	kubeVolume struct {
+		Name     string    `json:"name"`
+		EmptyDir *struct{} `json:"emptyDir,omitempty"`
+	}
+
+	kubeContainer struct {
+		Name      string `json:"name"`
+		Resources struct {
+			Requests map[string]string `json:"requests"`
+		} `json:"resources"`
+	}
+
+	kubePod struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			NodeName                      string          `json:"nodeName"`
+			Containers                    []kubeContainer `json:"containers"`
+			InitContainers                []kubeContainer `json:"initContainers"`
+			TerminationGracePeriodSeconds *int64          `json:"terminationGracePeriodSeconds"`
+			Volumes                       []kubeVolume    `json:"volumes"`
+			ReadinessGates                []struct {
+				ConditionType string `json:"conditionType"`
+			} `json:"readinessGates"`
+		} `json:"spec"`
+		Status struct {
+			Phase      string          `json:"phase"`
+			PodIP      string          `json:"podIP"`
+			Conditions []kubeCondition `json:"conditions"`
+		} `json:"status"`
+	}
+
+	kubePodList struct {
+		Items []kubePod `json:"items"`
+	}
+
+	kubeVolumeAttachment struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			Attacher string `json:"attacher"`
+			NodeName string `json:"nodeName"`
+			Source   struct {
+				PersistentVolumeName string `json:"persistentVolumeName"`
+			} `json:"source"`
+		} `json:"spec"`
+		Status struct {
+			Attached bool `json:"attached"`
+		} `json:"status"`
+	}
+
+	kubeVolumeAttachmentList struct {
+		Items []kubeVolumeAttachment `json:"items"`
+	}
+)
+
+func (n *kubeNode) hasTaint(key, effect string) bool {
+	for _, taint := range n.Spec.Taints {
+		if taint.Key == key && taint.Effect == effect {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *kubeNode) isReady() bool {
+	for _, condition := range n.Status.Conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == "True"
+		}
+	}
+	return false
+}
+
+func (n *kubeNode) isSchedulable() bool {
+	if n.Spec.Unschedulable || !n.isReady() {
+		return false
+	}
+
+	for _, taint := range n.Spec.Taints {
+		if taint.Effect == "NoSchedule" || taint.Effect == "NoExecute" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// controllerRef returns the owner reference of the controlling workload
+func (p *kubePod) controllerRef() *kubeOwnerReference {
+	for _, ownerRef := range p.Metadata.OwnerReferences {
+		if ownerRef.Controller != nil && *ownerRef.Controller {
+			return &ownerRef
+		}
+	}
+	return nil
+}
+
+func (p *kubePod) isDaemonSetPod() bool {
+	if ownerRef := p.controllerRef(); ownerRef != nil {
+		return ownerRef.Kind == "DaemonSet"
+	}
+	return false
+}
+
+func (p *kubePod) isMirrorPod() bool {
+	_, exists := p.Metadata.Annotations["kubernetes.io/config.mirror"]
+	return exists
+}
+
+// hasLocalStorage checks if the pod uses emptyDir volumes (data is lost on eviction)
+func (p *kubePod) hasLocalStorage() bool {
+	for _, volume := range p.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *kubePod) isTerminated() bool {
+	return p.Status.Phase == "Succeeded" || p.Status.Phase == "Failed"
+}
+
+func (p *kubePod) isReady() bool {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == "True"
+		}
+	}
+	return false
+}
+
+func (p *kubePod) hasReadinessGate(conditionType string) bool {
+	for _, readinessGate := range p.Spec.ReadinessGates {
+		if readinessGate.ConditionType == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *kubePod) conditionStatus(conditionType string) string {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return ""
+}
+
+// resourceRequests returns the effective cpu (cores) and memory (bytes) requests of the pod
+func (p *kubePod) resourceRequests() (cpu float64, memory float64) {
+	for _, container := range p.Spec.Containers {
+		cpu += parseQuantity(container.Resources.Requests["cpu"])
+		memory += parseQuantity(container.Resources.Requests["memory"])
+	}
+
+	// init containers run sequentially, the highest request is relevant
+	for _, container := range p.Spec.InitContainers {
+		if val := parseQuantity(container.Resources.Requests["cpu"]); val > cpu {
+			cpu = val
+		}
+		if val := parseQuantity(container.Resources.Requests["memory"]); val > memory {
+			memory = val
+		}
+	}
+
+	return
+}
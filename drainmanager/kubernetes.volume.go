@@ -0,0 +1,70 @@
+package drainmanager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	KubernetesVolumeDetachPollInterval = 5 * time.Second
+)
+
+// waitForVolumeDetach waits until no VolumeAttachment references the node anymore
+// or the deadline (derived from NotBefore of the ScheduledEvent) is reached
+func (m *DrainManagerKubernetes) waitForVolumeDetach(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
+	if m.Conf.Kubernetes.Drain.DryRun {
+		return true
+	}
+
+	deadline := eventDeadline(event, m.Conf.Kubernetes.Drain.VolumeDetach.Margin, m.Conf.Kubernetes.Drain.VolumeDetach.Timeout)
+	waitLogger := m.Logger.With(slog.String("node", m.nodeName), slog.Time("deadline", deadline))
+	waitLogger.Info("waiting for volume detach")
+
+	startTime := time.Now()
+	for {
+		volumes, err := m.attachedVolumes()
+		if err != nil {
+			waitLogger.Warn("unable to fetch volumeattachments", slog.Any("error", err))
+		} else if len(volumes) == 0 {
+			waitLogger.Info("all volumes detached", slog.Duration("duration", time.Since(startTime)))
+			return true
+		} else {
+			waitLogger.Info("volumes still attached", slog.Any("volumes", volumes))
+		}
+
+		if time.Now().Add(KubernetesVolumeDetachPollInterval).After(deadline) {
+			waitLogger.Warn("deadline reached, volumes still attached", slog.Any("volumes", volumes))
+			m.recordEvent(KubernetesEventTypeWarning, "VolumeDetachTimeout", fmt.Sprintf("volumes still attached after drain: %v", volumes))
+			return false
+		}
+
+		if !sleepContext(ctx, KubernetesVolumeDetachPollInterval) {
+			return false
+		}
+	}
+}
+
+// attachedVolumes returns the persistent volume names of all VolumeAttachments referencing the node
+func (m *DrainManagerKubernetes) attachedVolumes() ([]string, error) {
+	list := &kubeVolumeAttachmentList{}
+	if err := m.execGetJson(list, "volumeattachments.storage.k8s.io"); err != nil {
+		return nil, err
+	}
+
+	ret := []string{}
+	for _, volumeAttachment := range list.Items {
+		if volumeAttachment.Spec.NodeName == m.nodeName {
+			name := volumeAttachment.Spec.Source.PersistentVolumeName
+			if name == "" {
+				name = volumeAttachment.Metadata.Name
+			}
+			ret = append(ret, name)
+		}
+	}
+
+	return ret, nil
+}
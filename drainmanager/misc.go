@@ -0,0 +1,38 @@
+package drainmanager
+
+import (
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+// eventDeadline returns NotBefore of the ScheduledEvent minus margin,
+// if ScheduledEvent has no (parsable) NotBefore the fallback duration from now is used
+func eventDeadline(event *azuremetadata.AzureScheduledEvent, margin, fallback time.Duration) time.Time {
+	if event != nil {
+		if notBefore, err := event.NotBeforeTime(); err == nil && !notBefore.IsZero() {
+			return notBefore.Add(-margin)
+		}
+	}
+
+	return time.Now().Add(fallback)
+}
+
+func stringListContains(list []string, needle string) bool {
+	for _, val := range list {
+		if val == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func stringListRemove(list []string, needle string) []string {
+	ret := []string{}
+	for _, val := range list {
+		if val != needle {
+			ret = append(ret, val)
+		}
+	}
+	return ret
+}
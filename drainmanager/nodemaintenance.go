@@ -0,0 +1,196 @@
+package drainmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	NodeMaintenanceResource     = "nodemaintenances.nodemaintenance.medik8s.io"
+	NodeMaintenanceApiVersion   = "nodemaintenance.medik8s.io/v1beta1"
+	NodeMaintenanceNamePrefix   = "azure-scheduledevents-"
+	NodeMaintenancePhaseSuccess = "Succeeded"
+	NodeMaintenancePhaseFailed  = "Failed"
+
+	NodeMaintenancePollInterval = 10 * time.Second
+)
+
+type (
+	// DrainManagerNodeMaintenance delegates cordon and drain to the medik8s node-maintenance-operator
+	// by creating a NodeMaintenance resource for the node, all other features are inherited from kubernetes mode
+	DrainManagerNodeMaintenance struct {
+		DrainManagerKubernetes
+	}
+
+	kubeNodeMaintenance struct {
+		Metadata kubeObjectMeta `json:"metadata"`
+		Spec     struct {
+			NodeName string `json:"nodeName"`
+			Reason   string `json:"reason"`
+		} `json:"spec"`
+		Status struct {
+			Phase        string   `json:"phase"`
+			LastError    string   `json:"lastError"`
+			PendingPods  []string `json:"pendingPods"`
+			EvictionPods int      `json:"evictionPods"`
+			TotalPods    int      `json:"totalpods"`
+		} `json:"status"`
+	}
+)
+
+func (m *DrainManagerNodeMaintenance) Test() error {
+	if err := m.DrainManagerKubernetes.Test(); err != nil {
+		return err
+	}
+
+	if !m.execGet(NodeMaintenanceResource) {
+		return errors.New(`unable to list NodeMaintenance resources, is node-maintenance-operator installed?`)
+	}
+	return nil
+}
+
+func (m *DrainManagerNodeMaintenance) Taint(event *azuremetadata.AzureScheduledEvent) bool {
+	// node state (taints, unschedulable) is owned by node-maintenance-operator
+	m.Logger.Debug("skipping taint, node is handled by node-maintenance-operator", slog.String("node", m.nodeName))
+	return true
+}
+
+func (m *DrainManagerNodeMaintenance) Cordon(event *azuremetadata.AzureScheduledEvent) bool {
+	// node is cordoned by node-maintenance-operator
+	m.Logger.Debug("skipping cordon, handled by node-maintenance-operator", slog.String("node", m.nodeName))
+	return true
+}
+
+func (m *DrainManagerNodeMaintenance) Drain(event *azuremetadata.AzureScheduledEvent) bool {
+	return m.runDrain(event, m.drain)
+}
+
+func (m *DrainManagerNodeMaintenance) drain(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
+	if !m.preDrain(ctx, event) {
+		return false
+	}
+
+	m.runPreEvictionHooks(ctx, event)
+
+	m.recordEvent(KubernetesEventTypeWarning, "DrainStarted", fmt.Sprintf("creating NodeMaintenance for %v", scheduledEventMessage(event)))
+	if !m.createNodeMaintenance(event) || !m.waitForNodeMaintenance(ctx, event) {
+		m.recordEvent(KubernetesEventTypeWarning, "DrainFailed", fmt.Sprintf("NodeMaintenance not finished for %v", scheduledEventMessage(event)))
+		return false
+	}
+
+	if m.Conf.Kubernetes.Drain.VolumeDetach.Enable {
+		m.waitForVolumeDetach(ctx, event)
+	}
+
+	m.recordEvent(KubernetesEventTypeNormal, "DrainFinished", fmt.Sprintf("NodeMaintenance finished for %v", scheduledEventMessage(event)))
+
+	return true
+}
+
+func (m *DrainManagerNodeMaintenance) Preempt(event *azuremetadata.AzureScheduledEvent) bool {
+	m.recordEvent(KubernetesEventTypeWarning, "PreemptStarted", fmt.Sprintf("creating NodeMaintenance for %v", scheduledEventMessage(event)))
+	if !m.createNodeMaintenance(event) || !m.waitForNodeMaintenance(context.Background(), event) {
+		m.recordEvent(KubernetesEventTypeWarning, "PreemptFailed", fmt.Sprintf("NodeMaintenance not finished for %v", scheduledEventMessage(event)))
+		return false
+	}
+	m.recordEvent(KubernetesEventTypeNormal, "PreemptFinished", fmt.Sprintf("NodeMaintenance finished for %v", scheduledEventMessage(event)))
+
+	return true
+}
+
+func (m *DrainManagerNodeMaintenance) Terminate(event *azuremetadata.AzureScheduledEvent) bool {
+	ret := m.deleteNodeMaintenance()
+	if !m.DrainManagerKubernetes.Terminate(event) {
+		ret = false
+	}
+	return ret
+}
+
+func (m *DrainManagerNodeMaintenance) Uncordon() bool {
+	// node is uncordoned by node-maintenance-operator after NodeMaintenance is deleted
+	if !m.deleteNodeMaintenance() {
+		return false
+	}
+
+	return m.DrainManagerKubernetes.Uncordon()
+}
+
+func (m *DrainManagerNodeMaintenance) nodeMaintenanceName() string {
+	return NodeMaintenanceNamePrefix + m.nodeName
+}
+
+// createNodeMaintenance creates (or updates) the NodeMaintenance of the node with the EventId as reason
+func (m *DrainManagerNodeMaintenance) createNodeMaintenance(event *azuremetadata.AzureScheduledEvent) bool {
+	payload, err := json.Marshal(map[string]interface{}{
+		"apiVersion": NodeMaintenanceApiVersion,
+		"kind":       "NodeMaintenance",
+		"metadata": map[string]interface{}{
+			"name": m.nodeMaintenanceName(),
+			"labels": map[string]string{
+				KubernetesLabelName: m.nodeName,
+			},
+		},
+		"spec": map[string]interface{}{
+			"nodeName": m.nodeName,
+			"reason":   event.EventId,
+		},
+	})
+	if err != nil {
+		m.Logger.Error("unable to build NodeMaintenance", slog.Any("error", err))
+		return false
+	}
+
+	m.Logger.Info("create NodeMaintenance", slog.String("node", m.nodeName), slog.String("name", m.nodeMaintenanceName()), slog.String("reason", event.EventId))
+	return m.execWithInput(payload, "apply", "--filename=-")
+}
+
+// waitForNodeMaintenance waits until the node-maintenance-operator reports the drain as succeeded
+// or the deadline (NotBefore of the ScheduledEvent) is reached
+func (m *DrainManagerNodeMaintenance) waitForNodeMaintenance(ctx context.Context, event *azuremetadata.AzureScheduledEvent) bool {
+	if m.Conf.Kubernetes.Drain.DryRun {
+		return true
+	}
+
+	deadline := eventDeadline(event, 0, m.Conf.Kubernetes.NodeMaintenance.Timeout)
+	waitLogger := m.Logger.With(slog.String("node", m.nodeName), slog.String("name", m.nodeMaintenanceName()), slog.Time("deadline", deadline))
+	waitLogger.Info("waiting for NodeMaintenance")
+
+	for {
+		nodeMaintenance := &kubeNodeMaintenance{}
+		if err := m.execGetJson(nodeMaintenance, NodeMaintenanceResource, m.nodeMaintenanceName()); err != nil {
+			waitLogger.Warn("unable to fetch NodeMaintenance", slog.Any("error", err))
+		} else {
+			status := nodeMaintenance.Status
+			switch status.Phase {
+			case NodeMaintenancePhaseSuccess:
+				waitLogger.Info("NodeMaintenance succeeded")
+				return true
+			case NodeMaintenancePhaseFailed:
+				// operator retries the drain
+				waitLogger.Warn("NodeMaintenance failed, waiting for retry", slog.String("error", status.LastError))
+			default:
+				waitLogger.Info("NodeMaintenance in progress", slog.String("phase", status.Phase), slog.Int("totalPods", status.TotalPods), slog.Int("pendingPods", len(status.PendingPods)), slog.String("error", status.LastError))
+			}
+		}
+
+		if time.Now().Add(NodeMaintenancePollInterval).After(deadline) {
+			waitLogger.Warn("NodeMaintenance not finished before deadline")
+			return false
+		}
+		if !sleepContext(ctx, NodeMaintenancePollInterval) {
+			return false
+		}
+	}
+}
+
+// deleteNodeMaintenance deletes all NodeMaintenance resources created by this manager for the node
+func (m *DrainManagerNodeMaintenance) deleteNodeMaintenance() bool {
+	m.Logger.Info("delete NodeMaintenance", slog.String("node", m.nodeName))
+	return m.exec("delete", NodeMaintenanceResource, "--selector", fmt.Sprintf("%v=%v", KubernetesLabelName, m.nodeName), "--ignore-not-found=true")
+}
@@ -26,10 +26,46 @@ func (m *DrainManagerNoop) Test() error {
 	return nil
 }
 
+func (m *DrainManagerNoop) Taint(event *azuremetadata.AzureScheduledEvent) bool {
+	return true
+}
+
+func (m *DrainManagerNoop) Cordon(event *azuremetadata.AzureScheduledEvent) bool {
+	return true
+}
+
 func (m *DrainManagerNoop) Drain(event *azuremetadata.AzureScheduledEvent) bool {
 	return true
 }
 
+func (m *DrainManagerNoop) Preempt(event *azuremetadata.AzureScheduledEvent) bool {
+	return true
+}
+
+func (m *DrainManagerNoop) Terminate(event *azuremetadata.AzureScheduledEvent) bool {
+	return true
+}
+
 func (m *DrainManagerNoop) Uncordon() bool {
 	return true
 }
+
+func (m *DrainManagerNoop) DrainReport() string {
+	return ""
+}
+
+func (m *DrainManagerNoop) Paused() (bool, string, error) {
+	return false, "", nil
+}
+
+func (m *DrainManagerNoop) ScheduledEventDetected(event *azuremetadata.AzureScheduledEvent) {}
+
+func (m *DrainManagerNoop) ScheduledEventApproved(event *azuremetadata.AzureScheduledEvent) {}
+
+func (m *DrainManagerNoop) ApprovalGranted(event *azuremetadata.AzureScheduledEvent) bool {
+	return true
+}
+
+func (m *DrainManagerNoop) ScheduledEventCleared() {}
+
+func (m *DrainManagerNoop) Heartbeat(status HeartbeatStatus) {}
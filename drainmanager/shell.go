@@ -0,0 +1,47 @@
+package drainmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	slogio "github.com/utkuozdemir/go-slogio"
+	"github.com/webdevops/go-common/log/slogger"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+// execShellCommand executes the command via "sh -c", event information is passed as environment variables
+func execShellCommand(logger *slogger.Logger, command string, event *azuremetadata.AzureScheduledEvent) bool {
+	env := os.Environ()
+	if event != nil {
+		env = append(env, fmt.Sprintf("EVENT_ID=%v", event.EventId))
+		env = append(env, fmt.Sprintf("EVENT_SOURCE=%v", event.EventSource))
+		env = append(env, fmt.Sprintf("EVENT_STATUS=%v", event.EventStatus))
+		env = append(env, fmt.Sprintf("EVENT_TYPE=%v", event.EventType))
+		env = append(env, fmt.Sprintf("EVENT_NOTBEFORE=%v", event.NotBefore))
+		env = append(env, fmt.Sprintf("EVENT_RESOURCES=%v", strings.Join(event.Resources, " ")))
+		env = append(env, fmt.Sprintf("EVENT_RESOURCETYPE=%v", event.ResourceType))
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = env
+
+	cmdLogger := logger.With(slog.String("command", "sh"))
+	writer := &slogio.Writer{Log: cmdLogger.Slog(), Level: slogger.LevelInfo}
+	defer writer.Close()
+
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	logger.Debugf("EXEC: %v", cmd.String())
+	err := cmd.Run()
+	if err != nil {
+		cmdLogger.Error(err.Error())
+		return false
+	}
+
+	return true
+}
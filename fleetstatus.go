@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/drainmanager"
+)
+
+// runFleetStatus prints the heartbeat status of all nodes, returns exit code 1 if a manager is stale or missing
+func runFleetStatus() int {
+	drain := &drainmanager.DrainManagerKubernetes{
+		Conf:   Opts,
+		Logger: logger,
+	}
+
+	fleetStatus, err := drain.FleetStatus(FleetStatusOpts.Selector)
+	if err != nil {
+		logger.Error(fmt.Sprintf("unable to fetch fleet status: %v", err))
+		return 1
+	}
+
+	unhealthy := 0
+	for _, nodeStatus := range fleetStatus {
+		if nodeStatus.Status != drainmanager.FleetStatusOk {
+			unhealthy++
+		}
+	}
+
+	switch FleetStatusOpts.Output {
+	case "json":
+		output, err := json.MarshalIndent(fleetStatus, "", "  ")
+		if err != nil {
+			logger.Error(err.Error())
+			return 1
+		}
+		fmt.Println(string(output))
+	default:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(writer, "NODE\tSTATUS\tVERSION\tSTATE\tLAST HEARTBEAT\tLAST IMDS SUCCESS") // nolint:errcheck
+		for _, nodeStatus := range fleetStatus {
+			lastHeartbeat := ""
+			if nodeStatus.RenewTime != nil {
+				lastHeartbeat = fmt.Sprintf("%v ago", time.Since(*nodeStatus.RenewTime).Truncate(time.Second))
+			}
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\n", nodeStatus.Node, nodeStatus.Status, nodeStatus.Version, nodeStatus.State, lastHeartbeat, nodeStatus.LastApiSuccess) // nolint:errcheck
+		}
+		writer.Flush() // nolint:errcheck
+	}
+
+	if unhealthy > 0 {
+		logger.Warn(fmt.Sprintf("found %v nodes with stale or missing manager", unhealthy))
+		return 1
+	}
+
+	return 0
+}
@@ -3,6 +3,7 @@ module github.com/webdevops/azure-scheduledevents-manager
 go 1.26.1
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/containrrr/shoutrrr v0.8.0
 	github.com/fatih/color v1.18.0 // indirect
@@ -13,6 +14,7 @@ require (
 	github.com/prometheus/procfs v0.20.1 // indirect
 	github.com/utkuozdemir/go-slogio v0.1.0
 	github.com/webdevops/go-common v0.0.0-20260128195140-4fed4f1759f6
+	go.yaml.in/yaml/v2 v2.4.4
 	golang.org/x/net v0.52.0 // indirect
 	golang.org/x/sys v0.42.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
@@ -31,7 +33,6 @@ require (
 	github.com/pbnjay/memory v0.0.0-20210728143218-7b4eea64cf58 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
 	go.uber.org/automaxprocs v1.6.0 // indirect
-	go.yaml.in/yaml/v2 v2.4.4 // indirect
 	golang.org/x/sync v0.20.0 // indirect
 	golang.org/x/tools v0.42.0 // indirect
 )
@@ -3,11 +3,10 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
 	"runtime"
-	"strings"
 	"sync/atomic"
 
 	flags "github.com/jessevdk/go-flags"
@@ -17,6 +16,7 @@ import (
 	"github.com/webdevops/azure-scheduledevents-manager/config"
 	"github.com/webdevops/azure-scheduledevents-manager/drainmanager"
 	"github.com/webdevops/azure-scheduledevents-manager/manager"
+	"github.com/webdevops/azure-scheduledevents-manager/sdnotify"
 )
 
 const (
@@ -24,8 +24,10 @@ const (
 )
 
 var (
-	argparser *flags.Parser
-	Opts      config.Opts
+	argparser       *flags.Parser
+	Opts            config.Opts
+	FleetStatusOpts config.FleetStatusOpts
+	ConfigDumpOpts  config.ConfigDumpOpts
 
 	// Git version information
 	gitCommit = "<unknown>"
@@ -40,6 +42,15 @@ func main() {
 	initArgparser()
 	initLogger()
 
+	if argparser.Active != nil {
+		switch argparser.Active.Name {
+		case "fleet-status":
+			os.Exit(runFleetStatus())
+		case "config":
+			os.Exit(runConfigCommand(argparser.Active.Active.Name))
+		}
+	}
+
 	logger.Infof("starting azure-scheduledevents-manager v%s (%s; %s; by %v at %v)", gitTag, gitCommit, runtime.Version(), Author, buildDate)
 	logger.Info(string(Opts.GetJson()))
 	initSystem()
@@ -53,11 +64,16 @@ func main() {
 	}
 	azureMetadataClient.Init()
 
-	if Opts.Instance.VmNodeName == "" {
-		instanceMetadata, err := azureMetadataClient.FetchInstanceMetadata()
-		if err != nil {
+	instanceMetadata, err := azureMetadataClient.FetchInstanceMetadata()
+	if err != nil {
+		if Opts.Instance.VmNodeName == "" || (Opts.Drain.Enable && (Opts.Drain.Mode == "kubernetes" || Opts.Drain.Mode == "nodemaintenance") && Opts.Kubernetes.NodeName == "") {
 			logger.Fatal(err.Error())
 		}
+		logger.Warn("unable to fetch instance metadata", slog.Any("error", err))
+		instanceMetadata = nil
+	}
+
+	if Opts.Instance.VmNodeName == "" {
 		logger.Infof("detecting VM resource name")
 		Opts.Instance.VmNodeName = instanceMetadata.Compute.Name
 	} else {
@@ -69,6 +85,8 @@ func main() {
 		Conf:                Opts,
 		Logger:              logger,
 		AzureMetadataClient: azureMetadataClient,
+		InstanceMetadata:    instanceMetadata,
+		Version:             gitTag,
 	}
 	scheduledEventsManager.Init()
 	scheduledEventsManager.OnClear = func() {
@@ -85,12 +103,23 @@ func main() {
 		switch Opts.Drain.Mode {
 		case "kubernetes":
 			logger.Infof("start \"kubernetes\" mode")
-			logger.Infof("using Kubernetes nodename: %v", Opts.Kubernetes.NodeName)
 			drain := &drainmanager.DrainManagerKubernetes{
 				Conf:   Opts,
 				Logger: logger,
+				Notify: scheduledEventsManager.SendNotification,
+			}
+			initKubernetesDrainManager(drain, instanceMetadata)
+			scheduledEventsManager.DrainManager = drain
+		case "nodemaintenance":
+			logger.Infof("start \"nodemaintenance\" mode")
+			drain := &drainmanager.DrainManagerNodeMaintenance{
+				DrainManagerKubernetes: drainmanager.DrainManagerKubernetes{
+					Conf:   Opts,
+					Logger: logger,
+					Notify: scheduledEventsManager.SendNotification,
+				},
 			}
-			drain.SetInstanceName(Opts.Kubernetes.NodeName)
+			initKubernetesDrainManager(&drain.DrainManagerKubernetes, instanceMetadata)
 			scheduledEventsManager.DrainManager = drain
 		case "command":
 			logger.Infof("start \"command\" mode")
@@ -105,16 +134,57 @@ func main() {
 		}
 	}
 
+	if Opts.Oneshot.Enable {
+		os.Exit(runOneshot(&scheduledEventsManager))
+	}
+
+	if systemdNotifier := sdnotify.NewFromEnv(); systemdNotifier.Enabled() {
+		logger.Info("enabling systemd notify", slog.String("socket", systemdNotifier.SocketPath), slog.Duration("watchdog", systemdNotifier.WatchdogTimeout))
+		scheduledEventsManager.SystemdNotifier = systemdNotifier
+	}
+
 	logger.Infof("starting manager")
 	scheduledEventsManager.Start()
 
+	if Opts.Config.File != "" {
+		startConfigReloadSignalHandler(&scheduledEventsManager)
+	}
+
+	if Opts.Config.File != "" && Opts.Config.Watch {
+		logger.Info("watching config file for changes", slog.String("file", Opts.Config.File), slog.Duration("interval", Opts.Config.WatchInterval))
+		startConfigWatcher(&scheduledEventsManager)
+	}
+
 	logger.Infof("starting http server on %s", Opts.Server.Bind)
 	startHttpServer()
 }
 
+// initKubernetesDrainManager discovers and verifies the Kubernetes node of the VM
+func initKubernetesDrainManager(drain *drainmanager.DrainManagerKubernetes, instanceMetadata *azuremetadata.AzureMetadataInstanceResponse) {
+	drain.Init()
+
+	if Opts.Kubernetes.NodeName == "" {
+		logger.Infof("detecting Kubernetes nodename via providerID")
+		nodeName, err := drain.DiscoverNodeName(instanceMetadata)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		Opts.Kubernetes.NodeName = nodeName
+	}
+
+	logger.Infof("using Kubernetes nodename: %v", Opts.Kubernetes.NodeName)
+	drain.SetInstanceName(Opts.Kubernetes.NodeName)
+
+	if instanceMetadata != nil {
+		if err := drain.VerifyNode(instanceMetadata); err != nil {
+			logger.Fatalf("kubernetes node verification failed: %v", err)
+		}
+	}
+}
+
 func initArgparser() {
-	argparser = flags.NewParser(&Opts, flags.Default)
-	_, err := argparser.Parse()
+	var err error
+	argparser, err = parseArgs(&Opts, &FleetStatusOpts, &ConfigDumpOpts, flags.Default)
 
 	// check if there is an parse error
 	if err != nil {
@@ -122,72 +192,72 @@ func initArgparser() {
 		if ok := errors.As(err, &flagsErr); ok && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
 		} else {
+			if !ok {
+				fmt.Println(err)
+			}
 			fmt.Println()
 			argparser.WriteHelp(os.Stdout)
 			os.Exit(1)
 		}
 	}
 
-	// validate instanceUrl url
-	instanceUrl, err := url.Parse(Opts.Azure.InstanceApiUrl)
-	if err != nil {
+	// validated and reported by "config validate"
+	if argparser.Active != nil && argparser.Active.Name == "config" {
+		return
+	}
+
+	if err := Opts.Validate(); err != nil {
 		fmt.Println(err)
 		fmt.Println()
 		argparser.WriteHelp(os.Stdout)
 		os.Exit(1)
 	}
+}
 
-	switch strings.ToLower(instanceUrl.Scheme) {
-	case "http":
-		break
-	case "https":
-		break
-	default:
-		fmt.Printf("ApiURL scheme not allowed (must be http or https), got %v\n", Opts.Azure.InstanceApiUrl)
-		fmt.Println()
-		argparser.WriteHelp(os.Stdout)
-		os.Exit(1)
+func newArgparser(opts *config.Opts, fleetStatusOpts *config.FleetStatusOpts, configDumpOpts *config.ConfigDumpOpts, options flags.Options) *flags.Parser {
+	parser := flags.NewParser(opts, options)
+	parser.SubcommandsOptional = true
+	if _, err := parser.AddCommand("fleet-status", "List nodes with stale or missing managers", "Reads the heartbeat Leases (--kube.heartbeat.enable) of all nodes and lists nodes with stale or missing managers", fleetStatusOpts); err != nil {
+		panic(err)
 	}
 
-	// validate scheduledEventsUrl url
-	scheduledEventsUrl, err := url.Parse(Opts.Azure.ScheduledEventsApiUrl)
+	configCommand, err := parser.AddCommand("config", "Validate or dump the config", "Validates or dumps the effective config (arguments, env vars and config file)", &struct{}{})
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println()
-		argparser.WriteHelp(os.Stdout)
-		os.Exit(1)
+		panic(err)
+	}
+	if _, err := configCommand.AddCommand("validate", "Validate the config", "Validates the effective config including durations, drain mode requirements, notification urls, message template and presence of commands", &struct{}{}); err != nil {
+		panic(err)
+	}
+	if _, err := configCommand.AddCommand("dump", "Dump the effective config", "Dumps the effective config with redacted secrets (can be used as config file)", configDumpOpts); err != nil {
+		panic(err)
 	}
 
-	// validate --api-url scheme
-	switch strings.ToLower(scheduledEventsUrl.Scheme) {
-	case "http":
-		break
-	case "https":
-		break
-	default:
-		fmt.Printf("ApiURL scheme not allowed (must be http or https), got %v\n", Opts.Azure.ScheduledEventsApiUrl)
-		fmt.Println()
-		argparser.WriteHelp(os.Stdout)
-		os.Exit(1)
+	return parser
+}
+
+// parseArgs parses the arguments and the config file (--config) into opts (precedence: arguments > env vars > config file)
+func parseArgs(opts *config.Opts, fleetStatusOpts *config.FleetStatusOpts, configDumpOpts *config.ConfigDumpOpts, options flags.Options) (*flags.Parser, error) {
+	parser := newArgparser(opts, fleetStatusOpts, configDumpOpts, options)
+	if _, err := parser.Parse(); err != nil {
+		return parser, err
 	}
 
-	if Opts.Drain.Enable {
-		switch Opts.Drain.Mode {
-		case "kubernetes":
-			if Opts.Kubernetes.NodeName == "" {
-				fmt.Println("kubernetes node name must be set in kubernetes drain mode")
-				fmt.Println()
-				argparser.WriteHelp(os.Stdout)
-				os.Exit(1)
-			}
-		case "command":
-		default:
-			fmt.Println("drain enabled but no drain mode set")
-			fmt.Println()
-			argparser.WriteHelp(os.Stdout)
-			os.Exit(1)
-		}
+	if opts.Config.File == "" {
+		return parser, nil
 	}
+
+	fileArgs, err := config.FileArgs(opts.Config.File, parser)
+	if err != nil {
+		return parser, err
+	}
+
+	// parse again with options from config file in front of the arguments
+	*opts = config.Opts{}
+	*fleetStatusOpts = config.FleetStatusOpts{}
+	*configDumpOpts = config.ConfigDumpOpts{}
+	parser = newArgparser(opts, fleetStatusOpts, configDumpOpts, options)
+	_, err = parser.ParseArgs(append(fileArgs, os.Args[1:]...))
+	return parser, err
 }
 
 func startHttpServer() {
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+	"github.com/webdevops/azure-scheduledevents-manager/drainmanager"
+)
+
+// UpdateConfig validates the config and applies it with the next collect cycle,
+// changed options which are only used at startup keep their current value.
+// Invalid configs are rejected and the current config is kept.
+func (m *ScheduledEventsManager) UpdateConfig(conf config.Opts) error {
+	currentConf := m.config()
+	ignored := conf.KeepRestartOptions(currentConf)
+
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	resourceMatcher, err := NewResourceMatcher(conf, m.InstanceMetadata)
+	if err != nil {
+		return fmt.Errorf(`failed to setup resource matcher: %w`, err)
+	}
+
+	if len(ignored) > 0 {
+		m.Logger.Warn("changed options require a restart, keeping current values", slog.Any("options", ignored))
+	}
+
+	changes := config.Diff(currentConf, conf)
+	for _, change := range changes {
+		m.Logger.Info("config changed", slog.String("option", change.Option), slog.String("current", change.Current), slog.String("updated", change.Updated))
+	}
+	if len(changes) == 0 {
+		m.Logger.Info("config unchanged")
+	}
+
+	m.confLock.Lock()
+	defer m.confLock.Unlock()
+	m.pendingConf = &conf
+	m.pendingResourceMatcher = resourceMatcher
+
+	return nil
+}
+
+// applyPendingConfig swaps the config before a collect cycle (one cycle always uses one config)
+func (m *ScheduledEventsManager) applyPendingConfig() {
+	m.confLock.Lock()
+	defer m.confLock.Unlock()
+
+	if m.pendingConf == nil {
+		return
+	}
+
+	m.Conf = *m.pendingConf
+	m.resourceMatcher = m.pendingResourceMatcher
+	m.pendingConf = nil
+	m.pendingResourceMatcher = nil
+
+	if updater, ok := m.DrainManager.(drainmanager.ConfigUpdater); ok {
+		updater.UpdateConfig(m.Conf)
+	}
+
+	m.Logger.Info("applied new config", slog.Any("names", m.resourceMatcher.Names()), slog.String("emptyResources", m.Conf.Instance.EmptyResources))
+}
+
+// config returns the current config, used outside of the collect cycle (eg. preempt fast path and notifications)
+func (m *ScheduledEventsManager) config() config.Opts {
+	m.confLock.RLock()
+	defer m.confLock.RUnlock()
+	return m.Conf
+}
+
+// matcher returns the current resource matcher, used outside of the collect cycle (eg. preempt fast path)
+func (m *ScheduledEventsManager) matcher() *ResourceMatcher {
+	m.confLock.RLock()
+	defer m.confLock.RUnlock()
+	return m.resourceMatcher
+}
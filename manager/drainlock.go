@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+	"github.com/webdevops/azure-scheduledevents-manager/drainmanager"
+)
+
+type (
+	// lockedDrainManager serialises the node changes of the collect cycle and the preempt fast path,
+	// the drain managers are not safe for concurrent use. Preempt never waits for a running drain.
+	lockedDrainManager struct {
+		drainmanager.DrainManager
+		lock sync.Mutex
+	}
+)
+
+func (d *lockedDrainManager) Taint(event *azuremetadata.AzureScheduledEvent) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.Taint(event)
+}
+
+func (d *lockedDrainManager) Cordon(event *azuremetadata.AzureScheduledEvent) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.Cordon(event)
+}
+
+func (d *lockedDrainManager) Drain(event *azuremetadata.AzureScheduledEvent) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.Drain(event)
+}
+
+// Preempt runs immediately, a running drain (which might take minutes) is cancelled and the Preempt
+// runs without lock as the notice window of Preempt ScheduledEvents is only about 30 seconds
+func (d *lockedDrainManager) Preempt(event *azuremetadata.AzureScheduledEvent) bool {
+	if d.lock.TryLock() {
+		defer d.lock.Unlock()
+		return d.DrainManager.Preempt(event)
+	}
+
+	if canceler, ok := d.DrainManager.(drainmanager.DrainCanceler); ok {
+		canceler.CancelDrain()
+	}
+	return d.DrainManager.Preempt(event)
+}
+
+func (d *lockedDrainManager) Terminate(event *azuremetadata.AzureScheduledEvent) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.Terminate(event)
+}
+
+func (d *lockedDrainManager) Uncordon() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.Uncordon()
+}
+
+func (d *lockedDrainManager) ScheduledEventDetected(event *azuremetadata.AzureScheduledEvent) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.DrainManager.ScheduledEventDetected(event)
+}
+
+func (d *lockedDrainManager) ScheduledEventApproved(event *azuremetadata.AzureScheduledEvent) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.DrainManager.ScheduledEventApproved(event)
+}
+
+func (d *lockedDrainManager) ApprovalGranted(event *azuremetadata.AzureScheduledEvent) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.DrainManager.ApprovalGranted(event)
+}
+
+func (d *lockedDrainManager) ScheduledEventCleared() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.DrainManager.ScheduledEventCleared()
+}
+
+// UpdateConfig passes reloaded configs to the drain manager if it supports them
+func (d *lockedDrainManager) UpdateConfig(conf config.Opts) {
+	if updater, ok := d.DrainManager.(drainmanager.ConfigUpdater); ok {
+		updater.UpdateConfig(conf)
+	}
+}
@@ -3,6 +3,9 @@ package manager
 import (
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/containrrr/shoutrrr"
@@ -12,22 +15,63 @@ import (
 	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
 	"github.com/webdevops/azure-scheduledevents-manager/config"
 	"github.com/webdevops/azure-scheduledevents-manager/drainmanager"
+	"github.com/webdevops/azure-scheduledevents-manager/sdnotify"
+)
+
+const (
+	EventTypePreempt   = "Preempt"
+	EventTypeTerminate = "Terminate"
 )
 
 type (
 	ScheduledEventsManager struct {
 		apiErrorCount int
+		detectedEvent string
+		eventCleared  bool
+		nodeTainted   bool
+		nodeCordoned  bool
 		nodeDrained   bool
 		nodeUncordon  bool
 
+		// node is gone after Terminate ScheduledEvent, no uncordon anymore
+		nodeTerminated bool
+
+		lastApiSuccess time.Time
+
+		paused        bool
+		pausedEventId string
+
+		// READY=1 was sent to systemd
+		systemdReady bool
+
+		// status published by heartbeat, updated after each collect cycle and renewed by heartbeat ticker
+		heartbeatStatus atomic.Pointer[drainmanager.HeartbeatStatus]
+
+		// start time of the running collect cycle (unix nanoseconds, 0 = no collect cycle running)
+		collectStarted atomic.Int64
+
+		// actions and error of the current collect cycle (oneshot mode)
+		decisions    []Decision
+		collectError error
+
 		OnClear           func()
 		OnScheduledEvent  func()
 		OnAfterDrainEvent func()
 
 		Conf                config.Opts
 		Logger              *slogger.Logger
+		Version             string
 		AzureMetadataClient *azuremetadata.AzureMetadata
+		InstanceMetadata    *azuremetadata.AzureMetadataInstanceResponse
 		DrainManager        drainmanager.DrainManager
+		SystemdNotifier     *sdnotify.Notifier
+
+		resourceMatcher *ResourceMatcher
+
+		// config updates (UpdateConfig) are applied with the next collect cycle
+		confLock               sync.RWMutex
+		pendingConf            *config.Opts
+		pendingResourceMatcher *ResourceMatcher
 
 		prometheus struct {
 			documentIncarnation *prometheus.GaugeVec
@@ -36,12 +80,25 @@ type (
 			eventApproval       *prometheus.GaugeVec
 			request             *prometheus.HistogramVec
 			requestErrors       *prometheus.CounterVec
+
+			preemptRemaining  *prometheus.GaugeVec
+			preemptDuration   *prometheus.GaugeVec
+			preemptNoticeUsed *prometheus.GaugeVec
+
+			paused *prometheus.GaugeVec
 		}
 	}
 )
 
 func (m *ScheduledEventsManager) Init() {
 	m.initMetrics()
+
+	resourceMatcher, err := NewResourceMatcher(m.Conf, m.InstanceMetadata)
+	if err != nil {
+		m.Logger.Fatalf(`failed to setup resource matcher: %v`, err)
+	}
+	m.resourceMatcher = resourceMatcher
+	m.Logger.Info("matching ScheduledEvent resources", slog.Any("names", m.resourceMatcher.Names()), slog.String("emptyResources", m.Conf.Instance.EmptyResources))
 }
 
 func (m *ScheduledEventsManager) initMetrics() {
@@ -106,16 +163,35 @@ func (m *ScheduledEventsManager) initMetrics() {
 		[]string{},
 	)
 	prometheus.MustRegister(m.prometheus.requestErrors)
+
+	m.initPreemptMetrics()
+	m.initPauseMetrics()
 }
 
 func (m *ScheduledEventsManager) Start() {
+	preemptFastPath := m.Conf.Drain.Enable && m.Conf.Drain.Preempt.FastPath
+	if preemptFastPath {
+		// drain manager is used by collect cycle and preempt fast path
+		m.DrainManager = &lockedDrainManager{DrainManager: m.DrainManager}
+	}
+
+	m.startSystemdWatchdog()
+	m.startHeartbeat()
+
 	go func() {
+		if preemptFastPath {
+			// preempt fast path starts without startup delay, but not before the drain manager is tested
+			m.testDrainManager()
+
+			m.Logger.Info("starting preempt fast path", slog.Duration("scrapeTime", m.Conf.Drain.Preempt.ScrapeTime))
+			m.startPreemptFastPath()
+		}
+
 		// delay startup a little bit
 		time.Sleep(m.Conf.Startup.Delay)
 
-		// test drain manager
-		if err := m.DrainManager.Test(); err != nil {
-			m.Logger.Fatalf(`failed to test drain manager: %v`, err)
+		if !preemptFastPath {
+			m.testDrainManager()
 		}
 
 		for {
@@ -125,10 +201,34 @@ func (m *ScheduledEventsManager) Start() {
 	}()
 }
 
+func (m *ScheduledEventsManager) testDrainManager() {
+	if err := m.DrainManager.Test(); err != nil {
+		m.Logger.Fatalf(`failed to test drain manager: %v`, err)
+	}
+}
+
 func (m *ScheduledEventsManager) collect() {
 	var approveEvent *azuremetadata.AzureScheduledEvent
+	var preemptEvent *azuremetadata.AzureScheduledEvent
+	triggerTaint := false
+	triggerCordon := false
 	triggerDrain := false
+	preemptInProgress := false
+
+	m.collectStarted.Store(time.Now().UnixNano())
+	defer m.collectStarted.Store(0)
+
+	m.applyPendingConfig()
+	m.decisions = []Decision{}
+	m.collectError = nil
+
+	defer func() {
+		m.heartbeat()
+		m.systemdNotify(approveEvent)
+	}()
 
+	taintTimeThreshold := float64(time.Now().Add(m.Conf.Drain.Taint.NotBefore).Unix())
+	cordonTimeThreshold := float64(time.Now().Add(m.Conf.Drain.Cordon.NotBefore).Unix())
 	drainTimeThreshold := float64(time.Now().Add(m.Conf.Drain.NotBefore).Unix())
 
 	startTime := time.Now()
@@ -139,6 +239,7 @@ func (m *ScheduledEventsManager) collect() {
 
 		if m.Conf.Azure.ErrorThreshold <= 0 || m.apiErrorCount <= m.Conf.Azure.ErrorThreshold {
 			m.Logger.Errorf("failed API call: %s", err)
+			m.collectError = fmt.Errorf("failed API call: %w", err)
 			return
 		} else {
 			panic(err.Error())
@@ -152,6 +253,7 @@ func (m *ScheduledEventsManager) collect() {
 
 	// reset error count and metrics
 	m.apiErrorCount = 0
+	m.lastApiSuccess = time.Now()
 	m.prometheus.event.Reset()
 
 	if len(scheduledEvents.Events) == 0 {
@@ -159,6 +261,39 @@ func (m *ScheduledEventsManager) collect() {
 		m.prometheus.eventApproval.Reset()
 	}
 
+	handleCurrentNodeEvent := func(event *azuremetadata.AzureScheduledEvent, eventValue float64) {
+		if m.isPreemptFastPathEvent(event) {
+			// handled by preempt fast path, no drain and no approval
+			m.Logger.Debug("skipping Preempt ScheduledEvent, handled by fast path", slog.String("eventID", event.EventId))
+			preemptInProgress = true
+			preemptEvent = event
+			return
+		}
+
+		approveEvent = event
+		if stringArrayContainsCi(m.Conf.Drain.Events, event.EventType) {
+			if m.Conf.Drain.Taint.Enable {
+				if m.Conf.Drain.Taint.NotBefore <= 0 || eventValue == 1 || taintTimeThreshold >= eventValue {
+					triggerTaint = true
+				}
+			}
+
+			if m.Conf.Drain.Cordon.Enable {
+				if eventValue == 1 || cordonTimeThreshold >= eventValue {
+					triggerCordon = true
+				}
+			}
+
+			if eventValue == 1 || drainTimeThreshold >= eventValue {
+				if m.OnScheduledEvent != nil {
+					m.OnScheduledEvent()
+				}
+
+				triggerDrain = true
+			}
+		}
+	}
+
 	for _, row := range scheduledEvents.Events {
 		event := row
 		eventValue, err := event.NotBeforeUnixTimestamp()
@@ -201,18 +336,9 @@ func (m *ScheduledEventsManager) collect() {
 						"eventSource":  event.EventSource,
 					}).Set(eventValue)
 
-				if m.Conf.Instance.VmNodeName != "" && resource == m.Conf.Instance.VmNodeName {
+				if m.resourceMatcher.Match(resource) {
 					resourceLogger.Infof("detected ScheduledEvent %v with %v by %v in %v for current node", event.EventId, event.EventSource, event.EventType, time.Until(time.Unix(int64(eventValue), 0)).String()) //nolint:gosimple
-					approveEvent = &event
-					if eventValue == 1 || drainTimeThreshold >= eventValue {
-						if stringArrayContainsCi(m.Conf.Drain.Events, event.EventType) {
-							if m.OnScheduledEvent != nil {
-								m.OnScheduledEvent()
-							}
-
-							triggerDrain = true
-						}
-					}
+					handleCurrentNodeEvent(&event, eventValue)
 				}
 			}
 		} else {
@@ -228,6 +354,11 @@ func (m *ScheduledEventsManager) collect() {
 				}).Set(eventValue)
 
 			eventLogger.Debug("found ScheduledEvent")
+
+			if m.resourceMatcher.MatchEmpty() {
+				eventLogger.Infof("detected ScheduledEvent %v with %v by %v in %v without resources, handling it for current node (policy: %v)", event.EventId, event.EventSource, event.EventType, time.Until(time.Unix(int64(eventValue), 0)).String(), m.Conf.Instance.EmptyResources) //nolint:gosimple
+				handleCurrentNodeEvent(&event, eventValue)
+			}
 		}
 	}
 
@@ -238,27 +369,35 @@ func (m *ScheduledEventsManager) collect() {
 	} else {
 		m.Logger.Debug("found Azure ScheduledEvents", slog.Int("eventCount", len(scheduledEvents.Events)))
 		m.OnClear()
-
-		// if event is gone, ensure uncordon of node
-		if !m.nodeUncordon && m.DrainManager != nil {
-			m.Logger.Infof("ensuring uncordon of instance %v", m.instanceName())
-			if m.DrainManager.Uncordon() {
-				m.Logger.Infof("uncordon finished")
-				m.nodeDrained = false
-				m.nodeUncordon = true
-			} else {
-				m.Logger.Infof("uncordon failed")
-			}
-		}
 	}
 
-	// trigger clear event if no approve event is found or no events at all
-	if approveEvent == nil || len(scheduledEvents.Events) == 0 {
+	// trigger clear event if no approve event (or Preempt ScheduledEvent in progress) is found or no events at all
+	if (approveEvent == nil && !preemptInProgress) || len(scheduledEvents.Events) == 0 {
 		m.OnClear()
 	}
 
+	// Preempt ScheduledEvents handled by fast path are still reported to the drain manager (and never cleared while in progress)
+	detectionEvent := approveEvent
+	if detectionEvent == nil && preemptInProgress {
+		detectionEvent = preemptEvent
+	}
+	m.handleScheduledEventDetection(detectionEvent)
+	if approveEvent != nil {
+		m.recordDecision(DecisionDetected, approveEvent, true, fmt.Sprintf("taint: %v, cordon: %v, drain: %v", triggerTaint, triggerCordon, triggerDrain))
+	}
+
+	if m.Conf.Drain.Enable && m.checkPaused(approveEvent) {
+		m.recordDecision(DecisionPaused, approveEvent, true, "skipping drain, approval and uncordon")
+		return
+	}
+
+	// if event is gone, ensure uncordon of node (not while paused)
+	if len(scheduledEvents.Events) == 0 && !m.nodeUncordon && !m.nodeTerminated && m.DrainManager != nil {
+		m.ensureUncordon("no ScheduledEvents")
+	}
+
 	if m.Conf.Drain.Enable {
-		if approveEvent != nil && triggerDrain {
+		if approveEvent != nil && (triggerTaint || triggerCordon || triggerDrain) {
 			eventLogger := m.Logger.With(
 				slog.Group(
 					"event",
@@ -270,81 +409,276 @@ func (m *ScheduledEventsManager) collect() {
 				),
 			)
 
-			if !m.nodeDrained {
-				eventLogger.Info("ensuring drain of instance", slog.String("instance", m.instanceName()))
-				m.sendNotification("draining instance %v: upcoming Azure ScheduledEvent %v with %s by %s: %v", m.instanceName(), approveEvent.EventId, approveEvent.EventType, approveEvent.EventSource, approveEvent.Description)
-				m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "start").SetToCurrentTime()
+			if triggerTaint && !m.nodeTainted && !m.nodeDrained && m.DrainManager != nil {
+				eventLogger.Info("ensuring taint of instance", slog.String("instance", m.instanceName()))
+				if m.DrainManager.Taint(approveEvent) {
+					eventLogger.Info("tainted successfully")
+					m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "taint").SetToCurrentTime()
+					m.recordDecision(DecisionTaint, approveEvent, true, "")
+					m.nodeTainted = true
+					m.nodeUncordon = false
+				} else {
+					eventLogger.Info("taint failed")
+					m.recordDecision(DecisionTaint, approveEvent, false, "")
+				}
+			}
 
-				if m.Conf.Drain.WaitBeforeCmd.Seconds() >= 1 {
-					eventLogger.Info("wait before drain", slog.Duration("waitTime", m.Conf.Drain.WaitBeforeCmd))
-					time.Sleep(m.Conf.Drain.WaitBeforeCmd)
+			if triggerCordon && !m.nodeCordoned && !m.nodeDrained && m.DrainManager != nil {
+				eventLogger.Info("ensuring cordon of instance", slog.String("instance", m.instanceName()))
+				m.SendNotification("cordoning instance %v: upcoming Azure ScheduledEvent %v with %s by %s: %v", m.instanceName(), approveEvent.EventId, approveEvent.EventType, approveEvent.EventSource, approveEvent.Description)
+				if m.DrainManager.Cordon(approveEvent) {
+					eventLogger.Info("cordoned successfully")
+					m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "cordon").SetToCurrentTime()
+					m.recordDecision(DecisionCordon, approveEvent, true, "")
+					m.nodeCordoned = true
+					m.nodeUncordon = false
+				} else {
+					eventLogger.Info("cordon failed")
+					m.recordDecision(DecisionCordon, approveEvent, false, "")
 				}
+			}
 
-				if m.DrainManager != nil {
-					if m.DrainManager.Drain(approveEvent) {
-						eventLogger.Info("drained successfully")
-						m.nodeDrained = true
-						m.nodeUncordon = false
-					} else {
-						eventLogger.Info("drained failed")
+			if triggerDrain {
+				if !m.nodeDrained {
+					eventLogger.Info("ensuring drain of instance", slog.String("instance", m.instanceName()))
+					m.SendNotification("draining instance %v: upcoming Azure ScheduledEvent %v with %s by %s: %v", m.instanceName(), approveEvent.EventId, approveEvent.EventType, approveEvent.EventSource, approveEvent.Description)
+					m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "start").SetToCurrentTime()
+
+					if m.Conf.Drain.WaitBeforeCmd.Seconds() >= 1 {
+						eventLogger.Info("wait before drain", slog.Duration("waitTime", m.Conf.Drain.WaitBeforeCmd))
+						time.Sleep(m.Conf.Drain.WaitBeforeCmd)
 					}
-				}
 
-				if m.Conf.Drain.WaitAfterCmd.Seconds() >= 1 {
-					eventLogger.Info("wait after drain", slog.Duration("waitTime", m.Conf.Drain.WaitAfterCmd))
-					time.Sleep(m.Conf.Drain.WaitAfterCmd)
-				}
+					if m.DrainManager != nil {
+						if m.DrainManager.Drain(approveEvent) {
+							eventLogger.Info("drained successfully")
+							m.recordDecision(DecisionDrain, approveEvent, true, "")
+							m.nodeDrained = true
+							m.nodeUncordon = false
+						} else {
+							eventLogger.Info("drained failed")
+							m.recordDecision(DecisionDrain, approveEvent, false, "")
+						}
+					}
+
+					if m.Conf.Drain.WaitAfterCmd.Seconds() >= 1 {
+						eventLogger.Info("wait after drain", slog.Duration("waitTime", m.Conf.Drain.WaitAfterCmd))
+						time.Sleep(m.Conf.Drain.WaitAfterCmd)
+					}
+
+					if m.OnAfterDrainEvent != nil {
+						m.OnAfterDrainEvent()
+					}
 
-				if m.OnAfterDrainEvent != nil {
-					m.OnAfterDrainEvent()
+					m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "finish").SetToCurrentTime()
 				}
 
-				m.prometheus.eventDrain.WithLabelValues(approveEvent.EventId, "finish").SetToCurrentTime()
-			}
+				approved := !m.Conf.Azure.ApproveScheduledEvent
+				if m.Conf.Azure.ApproveScheduledEvent && m.DrainManager != nil && !m.DrainManager.ApprovalGranted(approveEvent) {
+					eventLogger.Info("approval of ScheduledEvent not granted yet")
+					m.recordDecision(DecisionApprovalPending, approveEvent, true, "approval not granted yet")
+				} else if m.Conf.Azure.ApproveScheduledEvent {
+					eventLogger.Info("approving ScheduledEvent")
+					if err := m.AzureMetadataClient.ApproveScheduledEvent(approveEvent); err == nil {
+						m.prometheus.eventApproval.WithLabelValues(approveEvent.EventId).SetToCurrentTime()
+						eventLogger.Info("event approved")
+						m.recordDecision(DecisionApprove, approveEvent, true, "")
+						if m.DrainManager != nil {
+							m.DrainManager.ScheduledEventApproved(approveEvent)
+						}
+						approved = true
+					} else {
+						eventLogger.Error("approval failed", slog.Any("error", err))
+						m.recordDecision(DecisionApprove, approveEvent, false, err.Error())
+					}
+				}
 
-			if m.Conf.Azure.ApproveScheduledEvent {
-				eventLogger.Info("approving ScheduledEvent")
-				if err := m.AzureMetadataClient.ApproveScheduledEvent(approveEvent); err == nil {
-					m.prometheus.eventApproval.WithLabelValues(approveEvent.EventId).SetToCurrentTime()
-					eventLogger.Info("event approved")
-				} else {
-					eventLogger.Error("approval failed", slog.Any("error", err))
+				if approved && m.nodeDrained && m.isTerminateEvent(approveEvent) {
+					m.handleTerminateEvent(approveEvent)
 				}
 			}
-		} else {
+		} else if !preemptInProgress && !m.nodeTerminated {
 			if !m.nodeUncordon && m.DrainManager != nil {
-				m.Logger.Info("ensuring uncordon of instance", slog.String("instance", m.instanceName()))
-				if m.DrainManager.Uncordon() {
-					m.Logger.Info("uncordon finished")
-					m.nodeDrained = false
-					m.nodeUncordon = true
-				} else {
-					m.Logger.Info("uncordon failed")
+				m.ensureUncordon("no ScheduledEvent for current node")
+			}
+		}
+	}
+}
+
+// ensureUncordon uncordons the node after the maintenance and sends the completion notification
+// including the summary of the drain report (finished by the drain manager after the maintenance)
+func (m *ScheduledEventsManager) ensureUncordon(reason string) {
+	m.Logger.Info("ensuring uncordon of instance", slog.String("instance", m.instanceName()))
+	maintenance := m.nodeTainted || m.nodeCordoned || m.nodeDrained
+
+	if !m.DrainManager.Uncordon() {
+		m.Logger.Info("uncordon failed")
+		m.recordDecision(DecisionUncordon, nil, false, reason)
+		return
+	}
+
+	m.Logger.Info("uncordon finished")
+	m.recordDecision(DecisionUncordon, nil, true, reason)
+
+	if report := m.DrainManager.DrainReport(); report != "" {
+		m.SendNotification("instance %v uncordoned, maintenance finished: %v", m.instanceName(), report)
+	} else if maintenance {
+		m.SendNotification("instance %v uncordoned, maintenance finished", m.instanceName())
+	}
+
+	m.resetNodeState()
+}
+
+// handleScheduledEventDetection informs the drain manager about new, changed or removed ScheduledEvents for the current node
+func (m *ScheduledEventsManager) handleScheduledEventDetection(event *azuremetadata.AzureScheduledEvent) {
+	if m.DrainManager == nil {
+		return
+	}
+
+	if event != nil {
+		eventKey := fmt.Sprintf("%v:%v:%v", event.EventId, event.EventStatus, event.NotBefore)
+		if m.detectedEvent != eventKey {
+			m.DrainManager.ScheduledEventDetected(event)
+			m.detectedEvent = eventKey
+			m.eventCleared = false
+		}
+	} else if !m.eventCleared {
+		m.DrainManager.ScheduledEventCleared()
+		m.detectedEvent = ""
+		m.eventCleared = true
+	}
+}
+
+// isTerminateEvent checks if the event terminates the VM and node cleanup is enabled
+func (m *ScheduledEventsManager) isTerminateEvent(event *azuremetadata.AzureScheduledEvent) bool {
+	return m.Conf.Drain.Terminate.Enable && strings.EqualFold(event.EventType, EventTypeTerminate)
+}
+
+// handleTerminateEvent runs the node cleanup once, afterwards the node is never uncordoned again
+func (m *ScheduledEventsManager) handleTerminateEvent(event *azuremetadata.AzureScheduledEvent) {
+	if m.nodeTerminated || m.DrainManager == nil {
+		return
+	}
+
+	m.Logger.Info("cleanup of terminated instance", slog.String("instance", m.instanceName()), slog.String("eventID", event.EventId))
+	if m.DrainManager.Terminate(event) {
+		m.Logger.Info("cleanup of terminated instance finished")
+		m.prometheus.eventDrain.WithLabelValues(event.EventId, "terminate").SetToCurrentTime()
+		m.SendNotification("instance %v cleaned up: Azure ScheduledEvent %v with %s by %s", m.instanceName(), event.EventId, event.EventType, event.EventSource)
+		m.recordDecision(DecisionTerminate, event, true, "")
+		m.nodeTerminated = true
+	} else {
+		m.Logger.Error("cleanup of terminated instance failed")
+		m.recordDecision(DecisionTerminate, event, false, "")
+	}
+}
+
+// heartbeat updates the status published via drain manager (version, last IMDS success and state of the manager),
+// the heartbeat is renewed immediately if the state changed
+func (m *ScheduledEventsManager) heartbeat() {
+	if m.DrainManager == nil {
+		return
+	}
+
+	status := &drainmanager.HeartbeatStatus{
+		Version:        m.Version,
+		State:          m.state(),
+		LastApiSuccess: m.lastApiSuccess,
+	}
+
+	if previous := m.heartbeatStatus.Swap(status); previous == nil || previous.State != status.State {
+		m.DrainManager.Heartbeat(*status)
+	}
+}
+
+// startHeartbeat renews the heartbeat every scrape time independent of collect cycles,
+// so long running drains are not shown as stale
+func (m *ScheduledEventsManager) startHeartbeat() {
+	if m.DrainManager == nil {
+		return
+	}
+
+	go func() {
+		hanging := false
+		for {
+			time.Sleep(m.config().Scrape.Time)
+
+			// lease becomes stale if the collect cycle is hanging, the cached status would look healthy forever
+			if duration, scrapeTimeout := m.collectHanging(); duration > 0 {
+				if !hanging {
+					m.Logger.Error(
+						"collect cycle is hanging, stopping heartbeat",
+						slog.Duration("duration", duration),
+						slog.Duration("scrapeTimeout", scrapeTimeout),
+					)
 				}
+				hanging = true
+				continue
+			}
+			hanging = false
+
+			// no heartbeat before first collect cycle
+			if status := m.heartbeatStatus.Load(); status != nil {
+				m.DrainManager.Heartbeat(*status)
 			}
 		}
+	}()
+}
+
+// state returns the current maintenance state of the node
+func (m *ScheduledEventsManager) state() string {
+	switch {
+	case m.paused:
+		return "paused"
+	case m.nodeTerminated:
+		return "terminated"
+	case m.nodeDrained:
+		return "drained"
+	case m.nodeCordoned:
+		return "cordoned"
+	case m.nodeTainted:
+		return "tainted"
+	case m.detectedEvent != "":
+		return "detected"
+	case m.apiErrorCount > 0:
+		return "apiError"
+	default:
+		return "idle"
 	}
 }
 
+func (m *ScheduledEventsManager) resetNodeState() {
+	m.nodeTainted = false
+	m.nodeCordoned = false
+	m.nodeDrained = false
+	m.nodeUncordon = true
+}
+
 func (m *ScheduledEventsManager) instanceName() string {
+	vmNodeName := m.config().Instance.VmNodeName
+
 	if m.DrainManager != nil {
 		drainManagerInstanceName := m.DrainManager.InstanceName()
 
-		if drainManagerInstanceName == m.Conf.Instance.VmNodeName {
+		if drainManagerInstanceName == vmNodeName {
 			return drainManagerInstanceName
 		} else {
-			return fmt.Sprintf("%v (vm: %v)", drainManagerInstanceName, m.Conf.Instance.VmNodeName)
+			return fmt.Sprintf("%v (vm: %v)", drainManagerInstanceName, vmNodeName)
 		}
 	}
 
-	return m.Conf.Instance.VmNodeName
+	return vmNodeName
+}
+
+func (m *ScheduledEventsManager) SendNotification(message string, args ...interface{}) {
+	m.sendNotificationTo(m.config().Notification.List, message, args...)
 }
 
-func (m *ScheduledEventsManager) sendNotification(message string, args ...interface{}) {
+func (m *ScheduledEventsManager) sendNotificationTo(urlList []string, message string, args ...interface{}) {
 	message = fmt.Sprintf(message, args...)
-	message = fmt.Sprintf(m.Conf.Notification.MsgTemplate, message)
+	message = fmt.Sprintf(m.config().Notification.MsgTemplate, message)
 
-	for _, url := range m.Conf.Notification.List {
+	for _, url := range urlList {
 		if err := shoutrrr.Send(url, message); err != nil {
 			m.Logger.Error("unable to send shoutrrr notification", slog.Any("error", err))
 		}
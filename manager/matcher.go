@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+)
+
+const (
+	EmptyResourcesPolicyIgnore = "ignore"
+	EmptyResourcesPolicyMatch  = "match"
+
+	// VMSS computer names are built from the computer name prefix and the instance id in base36 (6 chars),
+	// only for Uniform orchestration (Flexible VMSS use random suffixes)
+	vmssComputerNameSuffixLength = 6
+)
+
+var (
+	// resource id of VMSS instances with Uniform orchestration (Flexible VMSS instances are standalone VMs)
+	vmssUniformResourceIdRegexp = regexp.MustCompile(`(?i)/providers/Microsoft\.Compute/virtualMachineScaleSets/[^/]+/virtualMachines/[^/]+$`)
+)
+
+type (
+	ResourceMatcher struct {
+		names          []string
+		regexps        []*regexp.Regexp
+		emptyResources string
+	}
+)
+
+// NewResourceMatcher builds the list of names (VM name, computer name, VMSS instance names and aliases)
+// and regular expressions which identify the current VM in ScheduledEvent resources
+func NewResourceMatcher(conf config.Opts, instance *azuremetadata.AzureMetadataInstanceResponse) (*ResourceMatcher, error) {
+	matcher := &ResourceMatcher{
+		emptyResources: conf.Instance.EmptyResources,
+	}
+
+	matcher.addName(conf.Instance.VmNodeName)
+	for _, alias := range conf.Instance.Aliases {
+		matcher.addName(alias)
+	}
+
+	if instance != nil {
+		matcher.addName(instance.Compute.Name)
+		matcher.addName(instance.Compute.OsProfile.ComputerName)
+
+		if vmssName := instance.Compute.VMScaleSetName; vmssName != "" && isUniformVmssInstance(instance) {
+			for _, name := range []string{conf.Instance.VmNodeName, instance.Compute.OsProfile.ComputerName} {
+				if instanceId, ok := vmssInstanceIdFromComputerName(name); ok {
+					matcher.addName(fmt.Sprintf("%v_%v", vmssName, instanceId))
+				}
+			}
+		}
+	}
+
+	for _, val := range conf.Instance.Regexp {
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid regexp "%v": %w`, val, err)
+		}
+		matcher.regexps = append(matcher.regexps, re)
+	}
+
+	return matcher, nil
+}
+
+// Names returns all names (including regexps) used for matching
+func (r *ResourceMatcher) Names() []string {
+	ret := append([]string{}, r.names...)
+	for _, re := range r.regexps {
+		ret = append(ret, fmt.Sprintf("/%v/", re.String()))
+	}
+	return ret
+}
+
+// Match checks if ScheduledEvent resource belongs to the current VM
+func (r *ResourceMatcher) Match(resource string) bool {
+	if resource == "" {
+		return false
+	}
+
+	if stringArrayContainsCi(r.names, resource) {
+		return true
+	}
+
+	for _, re := range r.regexps {
+		if re.MatchString(resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchEmpty checks if ScheduledEvents without resources should be handled for the current VM
+func (r *ResourceMatcher) MatchEmpty() bool {
+	return r.emptyResources == EmptyResourcesPolicyMatch
+}
+
+func (r *ResourceMatcher) addName(name string) {
+	if name != "" && !stringArrayContainsCi(r.names, name) {
+		r.names = append(r.names, name)
+	}
+}
+
+// isUniformVmssInstance checks if the VM is an instance of a VMSS with Uniform orchestration
+// (resource id below the VMSS or, without resource id, VM name <vmss>_<instance id>)
+func isUniformVmssInstance(instance *azuremetadata.AzureMetadataInstanceResponse) bool {
+	if instance.Compute.ResourceID != "" {
+		return vmssUniformResourceIdRegexp.MatchString(instance.Compute.ResourceID)
+	}
+
+	instanceId, found := strings.CutPrefix(instance.Compute.Name, instance.Compute.VMScaleSetName+"_")
+	if !found {
+		return false
+	}
+
+	_, err := strconv.ParseInt(instanceId, 10, 64)
+	return err == nil
+}
+
+// vmssInstanceIdFromComputerName converts VMSS computer names (eg. vmssname00000C) to the instance id (eg. 12)
+func vmssInstanceIdFromComputerName(name string) (int64, bool) {
+	if len(name) <= vmssComputerNameSuffixLength {
+		return 0, false
+	}
+
+	suffix := strings.ToLower(name[len(name)-vmssComputerNameSuffixLength:])
+	instanceId, err := strconv.ParseInt(suffix, 36, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return instanceId, true
+}
@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/config"
+)
+
+func TestVmssInstanceIdFromComputerName(t *testing.T) {
+	tests := []struct {
+		computerName string
+		instanceId   int64
+		ok           bool
+	}{
+		{"aks-nodepool1-12345678-vmss000000", 0, true},
+		{"aks-nodepool1-12345678-vmss000001", 1, true},
+		{"vmssname00000C", 12, true},
+		{"vmssname00000c", 12, true},
+		{"vmssname00001A", 46, true},
+		{"vmssname0000ZZ", 1295, true},
+		{"vmssname000100", 1296, true},
+		{"vmss", 0, false},
+		{"000001", 0, false},
+		{"vmssname-0000_", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		instanceId, ok := vmssInstanceIdFromComputerName(test.computerName)
+		if ok != test.ok || instanceId != test.instanceId {
+			t.Errorf("vmssInstanceIdFromComputerName(%q): expected (%v, %v), got (%v, %v)", test.computerName, test.instanceId, test.ok, instanceId, ok)
+		}
+	}
+}
+
+func TestNewResourceMatcherVmss(t *testing.T) {
+	tests := []struct {
+		name         string
+		vmName       string
+		computerName string
+		vmssName     string
+		resourceId   string
+		expected     []string
+	}{
+		{
+			name:         "uniform",
+			vmName:       "aks-nodepool1-12345678-vmss_12",
+			computerName: "aks-nodepool1-12345678-vmss00000C",
+			vmssName:     "aks-nodepool1-12345678-vmss",
+			resourceId:   "/subscriptions/xxx/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/12",
+			expected:     []string{"aks-nodepool1-12345678-vmss_12", "aks-nodepool1-12345678-vmss00000C"},
+		},
+		{
+			name:         "uniform without resource id",
+			vmName:       "vmssname_46",
+			computerName: "vmssname00001A",
+			vmssName:     "vmssname",
+			expected:     []string{"vmssname_46", "vmssname00001A"},
+		},
+		{
+			name:         "uniform with instance name not derived from vm name",
+			vmName:       "vmssname_1296",
+			computerName: "vmssname000100",
+			vmssName:     "vmssname",
+			resourceId:   "/subscriptions/xxx/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmssname/virtualMachines/1296",
+			expected:     []string{"vmssname_1296", "vmssname000100"},
+		},
+		{
+			name:         "flexible",
+			vmName:       "vmssflex_1a2b3c4d",
+			computerName: "vmssflexA1B2C3",
+			vmssName:     "vmssflex",
+			resourceId:   "/subscriptions/xxx/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vmssflex_1a2b3c4d",
+			expected:     []string{"vmssflex_1a2b3c4d", "vmssflexA1B2C3"},
+		},
+		{
+			name:         "flexible without resource id",
+			vmName:       "vmssflex_1a2b3c4d",
+			computerName: "vmssflexA1B2C3",
+			vmssName:     "vmssflex",
+			expected:     []string{"vmssflex_1a2b3c4d", "vmssflexA1B2C3"},
+		},
+		{
+			name:         "standalone vm",
+			vmName:       "vm000001",
+			computerName: "vm000001",
+			resourceId:   "/subscriptions/xxx/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm000001",
+			expected:     []string{"vm000001"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := &azuremetadata.AzureMetadataInstanceResponse{}
+			instance.Compute.Name = test.vmName
+			instance.Compute.OsProfile.ComputerName = test.computerName
+			instance.Compute.VMScaleSetName = test.vmssName
+			instance.Compute.ResourceID = test.resourceId
+
+			matcher, err := NewResourceMatcher(config.Opts{}, instance)
+			if err != nil {
+				t.Fatalf("unable to build resource matcher: %v", err)
+			}
+
+			if names := matcher.Names(); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected names %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+const (
+	DecisionDetected        = "detected"
+	DecisionTaint           = "taint"
+	DecisionCordon          = "cordon"
+	DecisionDrain           = "drain"
+	DecisionApprove         = "approve"
+	DecisionApprovalPending = "approvalPending"
+	DecisionTerminate       = "terminate"
+	DecisionUncordon        = "uncordon"
+	DecisionPaused          = "paused"
+)
+
+type (
+	// State is the state of the node which is persisted between runs in oneshot mode
+	State struct {
+		DetectedEvent  string    `json:"detectedEvent,omitempty"`
+		EventCleared   bool      `json:"eventCleared"`
+		NodeTainted    bool      `json:"nodeTainted"`
+		NodeCordoned   bool      `json:"nodeCordoned"`
+		NodeDrained    bool      `json:"nodeDrained"`
+		NodeUncordon   bool      `json:"nodeUncordon"`
+		NodeTerminated bool      `json:"nodeTerminated"`
+		Paused         bool      `json:"paused"`
+		PausedEventId  string    `json:"pausedEventId,omitempty"`
+		ApiErrorCount  int       `json:"apiErrorCount"`
+		LastApiSuccess time.Time `json:"lastApiSuccess"`
+	}
+
+	// Decision is an action of a collect cycle (eg. drain or approval of a ScheduledEvent)
+	Decision struct {
+		Action    string `json:"action"`
+		EventId   string `json:"eventId,omitempty"`
+		EventType string `json:"eventType,omitempty"`
+		NotBefore string `json:"notBefore,omitempty"`
+		Success   bool   `json:"success"`
+		Message   string `json:"message,omitempty"`
+	}
+
+	// OneshotResult is the result of RunOnce
+	OneshotResult struct {
+		Instance  string     `json:"instance"`
+		State     string     `json:"state"`
+		Decisions []Decision `json:"decisions"`
+		Error     string     `json:"error,omitempty"`
+	}
+)
+
+// RunOnce runs exactly one collect cycle (oneshot mode), the state of the node is loaded from
+// and saved to the state file so drain, approval and uncordon work across runs
+func (m *ScheduledEventsManager) RunOnce(stateFile string) (result OneshotResult, err error) {
+	// no background polling in oneshot mode, Preempt ScheduledEvents are handled by the collect cycle
+	m.Conf.Drain.Preempt.FastPath = false
+
+	if err := m.loadState(stateFile); err != nil {
+		return m.oneshotResult(err), err
+	}
+
+	if m.DrainManager != nil {
+		if err := m.DrainManager.Test(); err != nil {
+			err = fmt.Errorf(`failed to test drain manager: %w`, err)
+			return m.oneshotResult(err), err
+		}
+	}
+
+	func() {
+		// collect panics if the API error threshold is reached
+		defer func() {
+			if r := recover(); r != nil {
+				m.collectError = fmt.Errorf("%v", r)
+			}
+		}()
+		m.collect()
+	}()
+	err = m.collectError
+
+	if stateErr := m.saveState(stateFile); stateErr != nil {
+		err = errors.Join(err, stateErr)
+	}
+
+	// failed actions are errors (exit code 1), even if other actions were successful
+	for _, decision := range m.decisions {
+		switch {
+		case decision.Success:
+		case decision.EventId != "":
+			err = errors.Join(err, fmt.Errorf(`%v of ScheduledEvent %v failed`, decision.Action, decision.EventId))
+		default:
+			err = errors.Join(err, fmt.Errorf(`%v failed`, decision.Action))
+		}
+	}
+
+	return m.oneshotResult(err), err
+}
+
+// EventHandled checks if a ScheduledEvent of the current node was handled by a successful action
+// (taint, cordon, drain, approve or terminate), detection, pause and uncordon are not counted
+func (r *OneshotResult) EventHandled() bool {
+	for _, decision := range r.Decisions {
+		if !decision.Success {
+			continue
+		}
+
+		switch decision.Action {
+		case DecisionTaint, DecisionCordon, DecisionDrain, DecisionApprove, DecisionTerminate:
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ScheduledEventsManager) oneshotResult(err error) OneshotResult {
+	result := OneshotResult{
+		Instance:  m.instanceName(),
+		State:     m.state(),
+		Decisions: m.decisions,
+	}
+
+	if result.Decisions == nil {
+		result.Decisions = []Decision{}
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// recordDecision records an action of the current collect cycle
+func (m *ScheduledEventsManager) recordDecision(action string, event *azuremetadata.AzureScheduledEvent, success bool, message string) {
+	decision := Decision{
+		Action:  action,
+		Success: success,
+		Message: message,
+	}
+
+	if event != nil {
+		decision.EventId = event.EventId
+		decision.EventType = event.EventType
+		decision.NotBefore = event.NotBefore
+	}
+
+	m.decisions = append(m.decisions, decision)
+}
+
+func (m *ScheduledEventsManager) loadState(stateFile string) error {
+	content, err := os.ReadFile(stateFile) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf(`unable to read state file "%v": %w`, stateFile, err)
+	}
+
+	state := State{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return fmt.Errorf(`unable to parse state file "%v": %w`, stateFile, err)
+	}
+
+	m.detectedEvent = state.DetectedEvent
+	m.eventCleared = state.EventCleared
+	m.nodeTainted = state.NodeTainted
+	m.nodeCordoned = state.NodeCordoned
+	m.nodeDrained = state.NodeDrained
+	m.nodeUncordon = state.NodeUncordon
+	m.nodeTerminated = state.NodeTerminated
+	m.paused = state.Paused
+	m.pausedEventId = state.PausedEventId
+	m.apiErrorCount = state.ApiErrorCount
+	m.lastApiSuccess = state.LastApiSuccess
+
+	return nil
+}
+
+func (m *ScheduledEventsManager) saveState(stateFile string) error {
+	state := State{
+		DetectedEvent:  m.detectedEvent,
+		EventCleared:   m.eventCleared,
+		NodeTainted:    m.nodeTainted,
+		NodeCordoned:   m.nodeCordoned,
+		NodeDrained:    m.nodeDrained,
+		NodeUncordon:   m.nodeUncordon,
+		NodeTerminated: m.nodeTerminated,
+		Paused:         m.paused,
+		PausedEventId:  m.pausedEventId,
+		ApiErrorCount:  m.apiErrorCount,
+		LastApiSuccess: m.lastApiSuccess,
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0750); err != nil {
+		return fmt.Errorf(`unable to create directory of state file "%v": %w`, stateFile, err)
+	}
+
+	// write atomically, the state file must not be truncated if the VM is rebooted
+	tmpFile := stateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, content, 0600); err != nil {
+		return fmt.Errorf(`unable to write state file "%v": %w`, stateFile, err)
+	}
+	if err := os.Rename(tmpFile, stateFile); err != nil {
+		return fmt.Errorf(`unable to write state file "%v": %w`, stateFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+func (m *ScheduledEventsManager) initPauseMetrics() {
+	m.prometheus.paused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_paused",
+			Help: "Azure ScheduledEvent manager paused (1 = no drain, approval and uncordon; error = last known state is used)",
+		},
+		[]string{"reason", "error"},
+	)
+	prometheus.MustRegister(m.prometheus.paused)
+}
+
+// checkPaused checks the pause switches of the drain manager, logs and notifies about changes
+// and skipped ScheduledEvents
+func (m *ScheduledEventsManager) checkPaused(event *azuremetadata.AzureScheduledEvent) bool {
+	if m.DrainManager == nil {
+		return false
+	}
+
+	paused, reason, err := m.DrainManager.Paused()
+
+	m.prometheus.paused.Reset()
+	if paused {
+		m.prometheus.paused.WithLabelValues(reason, strconv.FormatBool(err != nil)).Set(1)
+	} else {
+		m.prometheus.paused.WithLabelValues("", strconv.FormatBool(err != nil)).Set(0)
+	}
+
+	if paused != m.paused {
+		if paused {
+			m.Logger.Warn("PAUSED: automated maintenance handling is paused", slog.String("instance", m.instanceName()), slog.String("reason", reason))
+			m.SendNotification("automated maintenance handling of instance %v paused via %v", m.instanceName(), reason)
+		} else {
+			m.Logger.Info("automated maintenance handling resumed", slog.String("instance", m.instanceName()))
+			m.SendNotification("automated maintenance handling of instance %v resumed", m.instanceName())
+			m.pausedEventId = ""
+		}
+		m.paused = paused
+	}
+
+	if !paused {
+		return false
+	}
+
+	m.Logger.Warn("PAUSED: skipping drain, approval and uncordon", slog.String("instance", m.instanceName()), slog.String("reason", reason))
+	if event != nil && m.pausedEventId != event.EventId {
+		m.SendNotification("skipping drain of instance %v for Azure ScheduledEvent %v with %s by %s: paused via %v", m.instanceName(), event.EventId, event.EventType, event.EventSource, reason)
+		m.pausedEventId = event.EventId
+	}
+
+	return true
+}
@@ -0,0 +1,180 @@
+package manager
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+)
+
+func (m *ScheduledEventsManager) initPreemptMetrics() {
+	m.prometheus.preemptRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_preempt_remaining_seconds",
+			Help: "Azure ScheduledEvent Preempt fast path: remaining notice time until NotBefore per stage",
+		},
+		[]string{"eventID", "stage"},
+	)
+	prometheus.MustRegister(m.prometheus.preemptRemaining)
+
+	m.prometheus.preemptDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_preempt_duration_seconds",
+			Help: "Azure ScheduledEvent Preempt fast path: duration since detection per stage",
+		},
+		[]string{"eventID", "stage"},
+	)
+	prometheus.MustRegister(m.prometheus.preemptDuration)
+
+	m.prometheus.preemptNoticeUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "azure_scheduledevent_preempt_notice_used_ratio",
+			Help: "Azure ScheduledEvent Preempt fast path: used ratio of the notice window (detection until NotBefore)",
+		},
+		[]string{"eventID"},
+	)
+	prometheus.MustRegister(m.prometheus.preemptNoticeUsed)
+}
+
+// startPreemptFastPath polls ScheduledEvents with a short interval (without startup delay)
+// and handles Preempt ScheduledEvents of the current node immediately, node changes are
+// serialised with the collect cycle (lockedDrainManager)
+func (m *ScheduledEventsManager) startPreemptFastPath() {
+	handledEvents := map[string]bool{}
+
+	go func() {
+		for {
+			scheduledEvents, err := m.AzureMetadataClient.FetchScheduledEvents()
+			if err != nil {
+				m.Logger.Debug("failed API call in preempt fast path", slog.Any("error", err))
+			} else {
+				reportedEvents := map[string]bool{}
+				for _, row := range scheduledEvents.Events {
+					event := row
+					reportedEvents[event.EventId] = true
+					if handledEvents[event.EventId] || !m.isPreemptFastPathEvent(&event) {
+						continue
+					}
+
+					handledEvents[event.EventId] = true
+					m.handlePreemptEvent(&event)
+				}
+
+				// forget ScheduledEvents which are not reported anymore
+				for eventId := range handledEvents {
+					if !reportedEvents[eventId] {
+						delete(handledEvents, eventId)
+					}
+				}
+			}
+
+			time.Sleep(m.config().Drain.Preempt.ScrapeTime)
+		}
+	}()
+}
+
+// isPreemptFastPathEvent checks if the ScheduledEvent is a Preempt ScheduledEvent for the current node handled by fast path
+func (m *ScheduledEventsManager) isPreemptFastPathEvent(event *azuremetadata.AzureScheduledEvent) bool {
+	conf := m.config()
+	if !conf.Drain.Enable || !conf.Drain.Preempt.FastPath || !strings.EqualFold(event.EventType, EventTypePreempt) {
+		return false
+	}
+
+	// Preempt ScheduledEvents are only drained if enabled via --drain.events
+	if !stringArrayContainsCi(conf.Drain.Events, event.EventType) {
+		return false
+	}
+
+	resourceMatcher := m.matcher()
+	if len(event.Resources) == 0 {
+		return resourceMatcher.MatchEmpty()
+	}
+
+	for _, resource := range event.Resources {
+		if resourceMatcher.Match(resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *ScheduledEventsManager) handlePreemptEvent(event *azuremetadata.AzureScheduledEvent) {
+	detectionTime := time.Now()
+	notBefore, _ := event.NotBeforeTime()
+
+	eventLogger := m.Logger.With(
+		slog.Group(
+			"event",
+			slog.String("id", event.EventId),
+			slog.String("type", event.EventType),
+			slog.String("status", event.EventStatus),
+			slog.String("notBefore", event.NotBefore),
+			slog.String("source", event.EventSource),
+		),
+	)
+
+	observeStage := func(stage string) time.Duration {
+		remaining := time.Duration(0)
+		if !notBefore.IsZero() {
+			remaining = time.Until(notBefore)
+		}
+		m.prometheus.preemptRemaining.WithLabelValues(event.EventId, stage).Set(remaining.Seconds())
+		m.prometheus.preemptDuration.WithLabelValues(event.EventId, stage).Set(time.Since(detectionTime).Seconds())
+		return remaining
+	}
+
+	if m.DrainManager != nil {
+		if paused, reason, _ := m.DrainManager.Paused(); paused {
+			eventLogger.Warn("PAUSED: skipping preempt fast path", slog.String("instance", m.instanceName()), slog.String("reason", reason))
+			m.SendNotification("skipping preempt fast path of instance %v for Azure ScheduledEvent %v: paused via %v", m.instanceName(), event.EventId, reason)
+			return
+		}
+	}
+
+	noticeWindow := observeStage("detected")
+	eventLogger.Warn("detected Preempt ScheduledEvent, starting fast path", slog.String("instance", m.instanceName()), slog.Duration("remaining", noticeWindow))
+
+	if m.OnScheduledEvent != nil {
+		m.OnScheduledEvent()
+	}
+
+	conf := m.config()
+	notificationList := conf.Drain.Preempt.Notification
+	if len(notificationList) == 0 {
+		notificationList = conf.Notification.List
+	}
+	m.sendNotificationTo(
+		notificationList,
+		"PREEMPTION of instance %v: Azure ScheduledEvent %v by %s in %v, evicting pods now: %v",
+		m.instanceName(),
+		event.EventId,
+		event.EventSource,
+		noticeWindow.Truncate(time.Second).String(),
+		event.Description,
+	)
+
+	m.prometheus.eventDrain.WithLabelValues(event.EventId, "start").SetToCurrentTime()
+	if m.DrainManager != nil {
+		if m.DrainManager.Preempt(event) {
+			eventLogger.Info("preempt fast path finished")
+		} else {
+			eventLogger.Warn("preempt fast path failed")
+		}
+	}
+	m.prometheus.eventDrain.WithLabelValues(event.EventId, "finish").SetToCurrentTime()
+
+	remaining := observeStage("drained")
+	if noticeWindow > 0 {
+		m.prometheus.preemptNoticeUsed.WithLabelValues(event.EventId).Set((noticeWindow - remaining).Seconds() / noticeWindow.Seconds())
+	}
+
+	if m.OnAfterDrainEvent != nil {
+		m.OnAfterDrainEvent()
+	}
+
+	eventLogger.Info("preempt fast path done", slog.Duration("duration", time.Since(detectionTime)), slog.Duration("remaining", remaining))
+}
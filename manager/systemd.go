@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/webdevops/azure-scheduledevents-manager/azuremetadata"
+	"github.com/webdevops/azure-scheduledevents-manager/sdnotify"
+)
+
+const (
+	// default scrape timeout in scrapes (plus drain duration)
+	ScrapeTimeoutScrapes = 3
+)
+
+// systemdNotify sends the maintenance state (STATUS=) to systemd after each collect cycle,
+// READY=1 is sent after the first successful IMDS request
+func (m *ScheduledEventsManager) systemdNotify(event *azuremetadata.AzureScheduledEvent) {
+	if !m.SystemdNotifier.Enabled() {
+		return
+	}
+
+	var err error
+	status := m.systemdStatus(event)
+	ready := !m.systemdReady && !m.lastApiSuccess.IsZero()
+	if ready {
+		err = m.SystemdNotifier.Ready(status)
+	} else {
+		err = m.SystemdNotifier.Notify(sdnotify.Status(status))
+	}
+
+	if err != nil {
+		m.Logger.Warn("unable to notify systemd", slog.Any("error", err))
+		return
+	}
+
+	if ready {
+		m.Logger.Info("notified systemd about readiness")
+		m.systemdReady = true
+	}
+}
+
+// systemdStatus returns the maintenance state shown by systemctl status
+func (m *ScheduledEventsManager) systemdStatus(event *azuremetadata.AzureScheduledEvent) string {
+	state := m.state()
+
+	switch {
+	case event != nil:
+		return fmt.Sprintf("%v: %v", state, scheduledEventStatus(event))
+	case state == "apiError" && m.lastApiSuccess.IsZero():
+		return fmt.Sprintf("%v: no successful IMDS request yet", state)
+	case state == "apiError":
+		return fmt.Sprintf("%v: last successful IMDS request at %v", state, m.lastApiSuccess.UTC().Format(time.RFC3339))
+	default:
+		return fmt.Sprintf("%v: no ScheduledEvent", state)
+	}
+}
+
+// startSystemdWatchdog sends WATCHDOG=1 every half watchdog timeout as long as the manager is healthy,
+// the watchdog is not reset anymore if a collect cycle (including drain) hangs longer than the scrape timeout
+func (m *ScheduledEventsManager) startSystemdWatchdog() {
+	if !m.SystemdNotifier.WatchdogEnabled() {
+		return
+	}
+
+	go func() {
+		hanging := false
+		ticker := time.NewTicker(m.SystemdNotifier.WatchdogTimeout / 2)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if duration, scrapeTimeout := m.collectHanging(); duration > 0 {
+				if !hanging {
+					m.Logger.Error(
+						"collect cycle is hanging, stopping systemd watchdog notifications",
+						slog.Duration("duration", duration),
+						slog.Duration("scrapeTimeout", scrapeTimeout),
+					)
+				}
+				hanging = true
+				continue
+			}
+			hanging = false
+
+			if err := m.SystemdNotifier.Watchdog(); err != nil {
+				m.Logger.Warn("unable to notify systemd watchdog", slog.Any("error", err))
+			}
+		}
+	}()
+}
+
+// collectHanging returns the duration of the running collect cycle if it is running longer than the scrape timeout
+// (0 if not hanging) and the scrape timeout
+func (m *ScheduledEventsManager) collectHanging() (time.Duration, time.Duration) {
+	scrapeTimeout := m.scrapeTimeout()
+	if started := m.collectStarted.Load(); started != 0 {
+		if duration := time.Since(time.Unix(0, started)); duration > scrapeTimeout {
+			return duration, scrapeTimeout
+		}
+	}
+	return 0, scrapeTimeout
+}
+
+// scrapeTimeout returns the max duration of a collect cycle (including drain), defaults to a multiple
+// of the scrape time plus the time the drain is started before NotBefore and the waits of the drain command
+func (m *ScheduledEventsManager) scrapeTimeout() time.Duration {
+	conf := m.config()
+	if conf.Systemd.WatchdogScrapeTimeout > 0 {
+		return conf.Systemd.WatchdogScrapeTimeout
+	}
+
+	return ScrapeTimeoutScrapes*conf.Scrape.Time + conf.Drain.NotBefore + conf.Drain.WaitBeforeCmd + conf.Drain.WaitAfterCmd
+}
+
+func scheduledEventStatus(event *azuremetadata.AzureScheduledEvent) string {
+	notBefore := event.NotBefore
+	if notBefore == "" {
+		notBefore = "now"
+	}
+
+	return fmt.Sprintf("ScheduledEvent %v (EventType: %v, NotBefore: %v, EventStatus: %v)", event.EventId, event.EventType, notBefore, event.EventStatus)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/webdevops/azure-scheduledevents-manager/manager"
+)
+
+const (
+	OneshotExitCodeNoEvent      = 0
+	OneshotExitCodeError        = 1
+	OneshotExitCodeEventHandled = 2
+)
+
+// runOneshot runs exactly one collect cycle and prints the decisions as JSON,
+// returns exit code 0 if no ScheduledEvent was handled, 2 if a ScheduledEvent was handled by a successful action
+// and 1 on errors (including failed actions)
+func runOneshot(scheduledEventsManager *manager.ScheduledEventsManager) int {
+	logger.Info("running oneshot", slog.String("stateFile", Opts.Oneshot.StateFile))
+
+	result, err := scheduledEventsManager.RunOnce(Opts.Oneshot.StateFile)
+
+	output, jsonErr := json.MarshalIndent(result, "", "  ")
+	if jsonErr != nil {
+		logger.Error(fmt.Sprintf("unable to build oneshot result: %v", jsonErr))
+		return OneshotExitCodeError
+	}
+	fmt.Println(string(output))
+
+	switch {
+	case err != nil:
+		logger.Error("oneshot failed", slog.Any("error", err))
+		return OneshotExitCodeError
+	case result.EventHandled():
+		return OneshotExitCodeEventHandled
+	default:
+		return OneshotExitCodeNoEvent
+	}
+}
@@ -0,0 +1,99 @@
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvNotifySocket = "NOTIFY_SOCKET"
+	EnvWatchdogUsec = "WATCHDOG_USEC"
+	EnvWatchdogPid  = "WATCHDOG_PID"
+
+	StateReady    = "READY=1"
+	StateWatchdog = "WATCHDOG=1"
+	StatePrefix   = "STATUS="
+)
+
+type (
+	// Notifier sends sd_notify messages to the unix datagram socket of systemd
+	Notifier struct {
+		// path of the unix datagram socket, abstract sockets start with "@" (eg. NOTIFY_SOCKET)
+		SocketPath string
+
+		// watchdog timeout of the service (WatchdogSec, eg. WATCHDOG_USEC), 0 = watchdog disabled
+		WatchdogTimeout time.Duration
+	}
+)
+
+// NewFromEnv creates a Notifier for the socket and watchdog passed by systemd via env vars,
+// the Notifier is disabled if the env vars are missing or meant for another process (WATCHDOG_PID)
+func NewFromEnv() *Notifier {
+	// env vars are meant for another process (eg. if env vars are passed to child processes)
+	if pid := os.Getenv(EnvWatchdogPid); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return &Notifier{}
+	}
+
+	return &Notifier{
+		SocketPath:      os.Getenv(EnvNotifySocket),
+		WatchdogTimeout: watchdogTimeoutFromEnv(),
+	}
+}
+
+// Enabled checks if the service is run by systemd with notify support (Type=notify)
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.SocketPath != ""
+}
+
+// WatchdogEnabled checks if the watchdog of systemd is enabled (WatchdogSec)
+func (n *Notifier) WatchdogEnabled() bool {
+	return n.Enabled() && n.WatchdogTimeout > 0
+}
+
+// Notify sends the states (eg. READY=1, STATUS=...) as one message, does nothing if not enabled
+func (n *Notifier) Notify(states ...string) error {
+	if !n.Enabled() {
+		return nil
+	}
+
+	// leading "@" is translated to abstract socket address by the net package
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: n.SocketPath, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf(`unable to connect to notify socket "%v": %w`, n.SocketPath, err)
+	}
+	defer conn.Close() // nolint:errcheck
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf(`unable to send to notify socket "%v": %w`, n.SocketPath, err)
+	}
+
+	return nil
+}
+
+// Ready notifies systemd that the startup is finished
+func (n *Notifier) Ready(status string) error {
+	return n.Notify(StateReady, Status(status))
+}
+
+// Watchdog resets the watchdog timer of systemd
+func (n *Notifier) Watchdog() error {
+	return n.Notify(StateWatchdog)
+}
+
+// Status returns the STATUS= state with the status (single line) shown by systemctl status
+func Status(status string) string {
+	return StatePrefix + strings.ReplaceAll(status, "\n", " ")
+}
+
+func watchdogTimeoutFromEnv() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(EnvWatchdogUsec), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,118 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("unable to listen on notify socket: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close() // nolint:errcheck
+	})
+
+	t.Setenv(EnvNotifySocket, socketPath)
+	return conn
+}
+
+func expectMessage(t *testing.T, conn *net.UnixConn, expected string) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("unable to set read deadline: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	size, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("unable to read from notify socket: %v", err)
+	}
+
+	if message := string(buf[:size]); message != expected {
+		t.Errorf("expected message %q, got %q", expected, message)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	conn := listenNotifySocket(t)
+	t.Setenv(EnvWatchdogUsec, "30000000")
+	t.Setenv(EnvWatchdogPid, strconv.Itoa(os.Getpid()))
+
+	notifier := NewFromEnv()
+	if !notifier.Enabled() {
+		t.Fatal("expected notifier to be enabled")
+	}
+	if !notifier.WatchdogEnabled() {
+		t.Fatal("expected watchdog to be enabled")
+	}
+	if notifier.WatchdogTimeout != 30*time.Second {
+		t.Errorf("expected watchdog timeout of 30s, got %v", notifier.WatchdogTimeout)
+	}
+
+	if err := notifier.Ready("idle"); err != nil {
+		t.Fatalf("unable to send readiness: %v", err)
+	}
+	expectMessage(t, conn, "READY=1\nSTATUS=idle")
+
+	if err := notifier.Notify(Status("drained\nnode")); err != nil {
+		t.Fatalf("unable to send status: %v", err)
+	}
+	expectMessage(t, conn, "STATUS=drained node")
+
+	if err := notifier.Watchdog(); err != nil {
+		t.Fatalf("unable to send watchdog: %v", err)
+	}
+	expectMessage(t, conn, "WATCHDOG=1")
+}
+
+func TestNewFromEnvDisabled(t *testing.T) {
+	t.Run("missing env", func(t *testing.T) {
+		t.Setenv(EnvNotifySocket, "")
+		t.Setenv(EnvWatchdogUsec, "")
+		t.Setenv(EnvWatchdogPid, "")
+
+		notifier := NewFromEnv()
+		if notifier.Enabled() || notifier.WatchdogEnabled() {
+			t.Error("expected notifier to be disabled")
+		}
+
+		if err := notifier.Ready("idle"); err != nil {
+			t.Errorf("expected no error for disabled notifier, got %v", err)
+		}
+	})
+
+	t.Run("pid mismatch", func(t *testing.T) {
+		listenNotifySocket(t)
+		t.Setenv(EnvWatchdogUsec, "30000000")
+		t.Setenv(EnvWatchdogPid, strconv.Itoa(os.Getpid()+1))
+
+		notifier := NewFromEnv()
+		if notifier.Enabled() || notifier.WatchdogEnabled() {
+			t.Error("expected notifier to be disabled")
+		}
+	})
+
+	t.Run("watchdog disabled", func(t *testing.T) {
+		listenNotifySocket(t)
+		t.Setenv(EnvWatchdogUsec, "")
+		t.Setenv(EnvWatchdogPid, "")
+
+		notifier := NewFromEnv()
+		if !notifier.Enabled() {
+			t.Error("expected notifier to be enabled")
+		}
+		if notifier.WatchdogEnabled() {
+			t.Error("expected watchdog to be disabled")
+		}
+	})
+}